@@ -0,0 +1,591 @@
+// Package apiclient is a typed client for the Game Sharing Service REST API
+// described by openapi.json
+package apiclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Game is a game in the catalogue
+type Game struct {
+	ID          string    `json:"id"`
+	ImageID     string    `json:"imageID"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Genre       string    `json:"genre,omitempty"`
+	MinPlayers  int       `json:"minPlayers,omitempty"`
+	MaxPlayers  int       `json:"maxPlayers,omitempty"`
+	Controls    string    `json:"controls,omitempty"`
+	Version     string    `json:"version,omitempty"`
+	Enabled     bool      `json:"enabled"`
+	Thumbnail   bool      `json:"thumbnail,omitempty"`
+	UpdatedOn   time.Time `json:"updatedOn"`
+}
+
+// GameFilter searches the catalogue, zero values match every game
+type GameFilter struct {
+	// Matched against the name and description, ignoring case
+	Search string
+	Genre  string
+
+	// Only games meant for this many players
+	Players int
+}
+
+// encode returns the filter as a query string
+func (filter GameFilter) encode() string {
+	values := url.Values{}
+	if filter.Search != "" {
+		values.Set("q", filter.Search)
+	}
+	if filter.Genre != "" {
+		values.Set("genre", filter.Genre)
+	}
+	if filter.Players > 0 {
+		values.Set("players", strconv.Itoa(filter.Players))
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	return "?" + values.Encode()
+}
+
+// GameFields changes a game in the catalogue, nil fields are unchanged
+type GameFields struct {
+	// Required when adding a game, and cannot be changed
+	ID          *string `json:"id,omitempty"`
+	ImageID     *string `json:"imageID,omitempty"`
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Genre       *string `json:"genre,omitempty"`
+	MinPlayers  *int    `json:"minPlayers,omitempty"`
+	MaxPlayers  *int    `json:"maxPlayers,omitempty"`
+	Controls    *string `json:"controls,omitempty"`
+	Version     *string `json:"version,omitempty"`
+	Enabled     *bool   `json:"enabled,omitempty"`
+}
+
+// upload is a request body sent as is rather than encoded as JSON
+type upload struct {
+	contentType string
+	data        []byte
+}
+
+// State identifies a saved state or live game session
+type State struct {
+	ID          string    `json:"id"`
+	SavedOn     time.Time `json:"savedOn"`
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	Favourite   bool      `json:"favourite,omitempty"`
+
+	// Set when the saved state has a PNG preview
+	Preview bool `json:"preview,omitempty"`
+}
+
+// StatePage is a page of saved states
+type StatePage struct {
+	States []State `json:"states"`
+
+	// Passed as ListOptions.Cursor to get the next page, empty on the last page
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// ListOptions selects a page of saved states, zero values use the server's defaults
+type ListOptions struct {
+	// "savedOn" or "name"
+	Sort string
+
+	// "asc" or "desc"
+	Order string
+
+	Tag   string
+	From  time.Time
+	To    time.Time
+	Limit int
+
+	// NextCursor of the previous page
+	Cursor string
+}
+
+// encode returns the options as a query string
+func (options ListOptions) encode() string {
+	values := url.Values{}
+	for name, value := range map[string]string{"sort": options.Sort, "order": options.Order, "tag": options.Tag, "cursor": options.Cursor} {
+		if value != "" {
+			values.Set(name, value)
+		}
+	}
+	if !options.From.IsZero() {
+		values.Set("from", options.From.Format(time.RFC3339))
+	}
+	if !options.To.IsZero() {
+		values.Set("to", options.To.Format(time.RFC3339))
+	}
+	if options.Limit > 0 {
+		values.Set("limit", strconv.Itoa(options.Limit))
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	return "?" + values.Encode()
+}
+
+// StateAnnotations changes the annotations of a saved state, nil fields are unchanged
+type StateAnnotations struct {
+	Title       *string   `json:"title,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	Tags        *[]string `json:"tags,omitempty"`
+	Favourite   *bool     `json:"favourite,omitempty"`
+}
+
+// LatencyReport is the connection quality of a player in a live game session
+type LatencyReport struct {
+	UserID       string  `json:"userID"`
+	RTT          float64 `json:"rttMs"`
+	Jitter       float64 `json:"jitterMs"`
+	InputLatency float64 `json:"inputLatencyMs"`
+	Samples      int     `json:"samples"`
+}
+
+// User is a user of the versioned API
+type User struct {
+	ID string `json:"id"`
+}
+
+// Token is a bearer token issued to a user
+type Token struct {
+	UserID    string    `json:"userID"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Session is a live game session
+type Session struct {
+	ID      string   `json:"id"`
+	GameID  string   `json:"gameID"`
+	Owner   string   `json:"owner"`
+	Players []string `json:"players"`
+	Invited []string `json:"invited"`
+}
+
+// Hub is a live game session as shown to operators
+type Hub struct {
+	ID             int             `json:"id"`
+	GameID         string          `json:"gameID"`
+	Owner          string          `json:"owner"`
+	Players        []string        `json:"players"`
+	Clients        []LatencyReport `json:"clients"`
+	Paused         bool            `json:"paused"`
+	TickIntervalMs float64         `json:"tickIntervalMs"`
+	TicksPerSecond float64         `json:"ticksPerSecond"`
+	StartedOn      time.Time       `json:"startedOn"`
+	AgeSeconds     float64         `json:"ageSeconds"`
+	LastInputOn    *time.Time      `json:"lastInputOn"`
+}
+
+// Error is an error response from the API
+type Error struct {
+	// HTTP status of the response
+	Status int
+
+	// Stable error code, e.g. "state_not_found"
+	Code string `json:"code"`
+
+	// Human readable description
+	Message string `json:"message"`
+
+	// Time to wait before retrying a rate limited request
+	RetryAfter time.Duration
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", err.Status, err.Code, err.Message)
+}
+
+// Client calls the REST API of a Game Sharing Service
+type Client struct {
+	// BaseURL is the address of the REST API, e.g. "http://localhost:8080"
+	BaseURL string
+
+	// HTTPClient sends the requests, http.DefaultClient if nil
+	HTTPClient *http.Client
+
+	// Token is the bearer token sent to the /v1 API, set by Authenticate
+	// The admin methods need one of the service's admin tokens instead
+	Token string
+}
+
+// New returns a Client for the REST API at baseURL
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Games returns the games available to play
+func (c *Client) Games(ctx context.Context) ([]Game, error) {
+	games := []Game{}
+	_, err := c.do(ctx, http.MethodGet, escapePath("games"), nil, &games)
+	return games, err
+}
+
+// SearchGames returns the games available to play that match the filter
+func (c *Client) SearchGames(ctx context.Context, filter GameFilter) ([]Game, error) {
+	games := []Game{}
+	_, err := c.do(ctx, http.MethodGet, escapePath("games")+filter.encode(), nil, &games)
+	return games, err
+}
+
+// GameThumbnail returns the thumbnail uploaded for a game
+func (c *Client) GameThumbnail(ctx context.Context, gameID string) ([]byte, error) {
+	var thumbnail []byte
+	_, err := c.do(ctx, http.MethodGet, escapePath("v1", "games", gameID, "thumbnail"), nil, &thumbnail)
+	return thumbnail, err
+}
+
+// States returns a user's saved states for a game
+func (c *Client) States(ctx context.Context, gameID, userID string) ([]State, error) {
+	states := []State{}
+	_, err := c.do(ctx, http.MethodGet, escapePath("games", gameID, userID), nil, &states)
+	return states, err
+}
+
+// CreateState starts a new live game session for a user
+func (c *Client) CreateState(ctx context.Context, gameID, userID string) (*State, error) {
+	state := &State{}
+	_, err := c.do(ctx, http.MethodPut, escapePath("games", gameID, userID), nil, state)
+	return state, err
+}
+
+// LoadState starts a live game session from a saved state
+func (c *Client) LoadState(ctx context.Context, gameID, userID string, stateID int) (*State, error) {
+	state := &State{}
+	_, err := c.do(ctx, http.MethodGet, escapePath("games", gameID, userID, strconv.Itoa(stateID)), nil, state)
+	return state, err
+}
+
+// SaveState saves a live game session as a new state
+func (c *Client) SaveState(ctx context.Context, gameID, userID string, stateID int) (*State, error) {
+	state := &State{}
+	_, err := c.do(ctx, http.MethodPut, escapePath("games", gameID, userID, strconv.Itoa(stateID)), nil, state)
+	return state, err
+}
+
+// LatencyStats returns the connection quality of the players in a live game session
+func (c *Client) LatencyStats(ctx context.Context, gameID, userID string, stateID int) ([]LatencyReport, error) {
+	reports := []LatencyReport{}
+	_, err := c.do(ctx, http.MethodGet, escapePath("games", gameID, userID, strconv.Itoa(stateID), "stats"), nil, &reports)
+	return reports, err
+}
+
+// StatePreview returns the PNG preview of a state in a user's saved states
+func (c *Client) StatePreview(ctx context.Context, gameID, userID string, stateID int) ([]byte, error) {
+	var preview []byte
+	_, err := c.do(ctx, http.MethodGet, escapePath("games", gameID, userID, strconv.Itoa(stateID), "preview"), nil, &preview)
+	return preview, err
+}
+
+// Login creates the user if it does not exist, returning true if it was created
+func (c *Client) Login(ctx context.Context, userID string) (bool, error) {
+	status, err := c.do(ctx, http.MethodPost, escapePath("login", userID), nil, nil)
+	return status == http.StatusCreated, err
+}
+
+// Authenticate creates the user with the secret if it does not exist and uses a new bearer token for it
+// Existing users must give the secret they were created with
+func (c *Client) Authenticate(ctx context.Context, userID string, secret string) (*Token, error) {
+	token := &Token{}
+	body := struct {
+		ID     string `json:"id"`
+		Secret string `json:"secret"`
+	}{userID, secret}
+	if _, err := c.do(ctx, http.MethodPost, escapePath("v1", "users"), body, token); err != nil {
+		return nil, err
+	}
+	c.Token = token.Token
+	return token, nil
+}
+
+// CurrentUser returns the user the bearer token was issued to
+func (c *Client) CurrentUser(ctx context.Context) (*User, error) {
+	user := &User{}
+	_, err := c.do(ctx, http.MethodGet, escapePath("v1", "users", "me"), nil, user)
+	return user, err
+}
+
+// SavedStates returns a page of the authenticated user's saved states for a game
+func (c *Client) SavedStates(ctx context.Context, gameID string, options ListOptions) (*StatePage, error) {
+	page := &StatePage{}
+	_, err := c.do(ctx, http.MethodGet, escapePath("v1", "games", gameID, "states")+options.encode(), nil, page)
+	return page, err
+}
+
+// SavedState returns one of the authenticated user's saved states
+func (c *Client) SavedState(ctx context.Context, gameID string, stateID string) (*State, error) {
+	state := &State{}
+	_, err := c.do(ctx, http.MethodGet, escapePath("v1", "games", gameID, "states", stateID), nil, state)
+	return state, err
+}
+
+// AnnotateSavedState sets the title, description, tags or favourite of one of the authenticated user's saved states
+func (c *Client) AnnotateSavedState(ctx context.Context, gameID string, stateID string, annotations StateAnnotations) (*State, error) {
+	state := &State{}
+	_, err := c.do(ctx, http.MethodPatch, escapePath("v1", "games", gameID, "states", stateID), annotations, state)
+	return state, err
+}
+
+// DeleteSavedState removes one of the authenticated user's saved states
+func (c *Client) DeleteSavedState(ctx context.Context, gameID string, stateID string) error {
+	_, err := c.do(ctx, http.MethodDelete, escapePath("v1", "games", gameID, "states", stateID), nil, nil)
+	return err
+}
+
+// SavedStatePreview returns the PNG preview of one of the authenticated user's saved states
+func (c *Client) SavedStatePreview(ctx context.Context, gameID string, stateID string) ([]byte, error) {
+	var preview []byte
+	_, err := c.do(ctx, http.MethodGet, escapePath("v1", "games", gameID, "states", stateID, "preview"), nil, &preview)
+	return preview, err
+}
+
+// StartSession starts a new live game session for the authenticated user
+func (c *Client) StartSession(ctx context.Context, gameID string) (*Session, error) {
+	session := &Session{}
+	_, err := c.do(ctx, http.MethodPost, escapePath("v1", "games", gameID, "sessions"), struct{}{}, session)
+	return session, err
+}
+
+// LoadSession starts a live game session for the authenticated user from a saved state
+func (c *Client) LoadSession(ctx context.Context, gameID string, stateID string) (*Session, error) {
+	session := &Session{}
+	body := struct {
+		StateID string `json:"stateID"`
+	}{stateID}
+	_, err := c.do(ctx, http.MethodPost, escapePath("v1", "games", gameID, "sessions"), body, session)
+	return session, err
+}
+
+// Session returns a live game session
+func (c *Client) Session(ctx context.Context, gameID string, sessionID string) (*Session, error) {
+	session := &Session{}
+	_, err := c.do(ctx, http.MethodGet, escapePath("v1", "games", gameID, "sessions", sessionID), nil, session)
+	return session, err
+}
+
+// SaveSession saves a live game session as a new state in the authenticated user's list
+func (c *Client) SaveSession(ctx context.Context, gameID string, sessionID string) (*State, error) {
+	state := &State{}
+	_, err := c.do(ctx, http.MethodPost, escapePath("v1", "games", gameID, "sessions", sessionID, "saves"), nil, state)
+	return state, err
+}
+
+// SessionStats returns the connection quality of the players in a live game session
+func (c *Client) SessionStats(ctx context.Context, gameID string, sessionID string) ([]LatencyReport, error) {
+	reports := []LatencyReport{}
+	_, err := c.do(ctx, http.MethodGet, escapePath("v1", "games", gameID, "sessions", sessionID, "stats"), nil, &reports)
+	return reports, err
+}
+
+// InvitePlayer allows another user to play in the authenticated user's live game session
+func (c *Client) InvitePlayer(ctx context.Context, gameID string, sessionID string, userID string) (*Session, error) {
+	session := &Session{}
+	_, err := c.do(ctx, http.MethodPost, escapePath("v1", "games", gameID, "sessions", sessionID, "players"), User{ID: userID}, session)
+	return session, err
+}
+
+// AdminGames returns every game in the catalogue that matches the filter, including disabled ones
+func (c *Client) AdminGames(ctx context.Context, filter GameFilter) ([]Game, error) {
+	games := []Game{}
+	_, err := c.do(ctx, http.MethodGet, escapePath("v1", "admin", "games")+filter.encode(), nil, &games)
+	return games, err
+}
+
+// AdminGame returns a game in the catalogue, whether or not it is enabled
+func (c *Client) AdminGame(ctx context.Context, gameID string) (*Game, error) {
+	game := &Game{}
+	_, err := c.do(ctx, http.MethodGet, escapePath("v1", "admin", "games", gameID), nil, game)
+	return game, err
+}
+
+// CreateGame adds a game to the catalogue, disabled unless Enabled is set
+func (c *Client) CreateGame(ctx context.Context, fields GameFields) (*Game, error) {
+	game := &Game{}
+	_, err := c.do(ctx, http.MethodPost, escapePath("v1", "admin", "games"), fields, game)
+	return game, err
+}
+
+// UpdateGame changes the fields of a game in the catalogue, or enables or disables it
+func (c *Client) UpdateGame(ctx context.Context, gameID string, fields GameFields) (*Game, error) {
+	game := &Game{}
+	_, err := c.do(ctx, http.MethodPatch, escapePath("v1", "admin", "games", gameID), fields, game)
+	return game, err
+}
+
+// DeleteGame removes a game and its thumbnail from the catalogue
+func (c *Client) DeleteGame(ctx context.Context, gameID string) error {
+	_, err := c.do(ctx, http.MethodDelete, escapePath("v1", "admin", "games", gameID), nil, nil)
+	return err
+}
+
+// UploadGameThumbnail replaces the thumbnail of a game with a PNG, JPEG or GIF image
+func (c *Client) UploadGameThumbnail(ctx context.Context, gameID string, contentType string, image []byte) (*Game, error) {
+	game := &Game{}
+	_, err := c.do(ctx, http.MethodPut, escapePath("v1", "admin", "games", gameID, "thumbnail"), upload{contentType, image}, game)
+	return game, err
+}
+
+// Hubs returns the live game sessions of the instance, only those of the game unless gameID is empty
+func (c *Client) Hubs(ctx context.Context, gameID string) ([]Hub, error) {
+	path := escapePath("v1", "admin", "hubs")
+	if gameID != "" {
+		path += "?" + url.Values{"gameID": {gameID}}.Encode()
+	}
+	hubs := []Hub{}
+	_, err := c.do(ctx, http.MethodGet, path, nil, &hubs)
+	return hubs, err
+}
+
+// Hub returns a live game session
+func (c *Client) Hub(ctx context.Context, gameID string, hubID int) (*Hub, error) {
+	hub := &Hub{}
+	_, err := c.do(ctx, http.MethodGet, escapePath("v1", "admin", "games", gameID, "hubs", strconv.Itoa(hubID)), nil, hub)
+	return hub, err
+}
+
+// SaveHub saves a live game session as a new state in each of its players' lists
+func (c *Client) SaveHub(ctx context.Context, gameID string, hubID int) (*State, error) {
+	state := &State{}
+	_, err := c.do(ctx, http.MethodPost, escapePath("v1", "admin", "games", gameID, "hubs", strconv.Itoa(hubID), "saves"), nil, state)
+	return state, err
+}
+
+// PauseHub stops a live game session from ticking until it is resumed
+func (c *Client) PauseHub(ctx context.Context, gameID string, hubID int) (*Hub, error) {
+	hub := &Hub{}
+	_, err := c.do(ctx, http.MethodPost, escapePath("v1", "admin", "games", gameID, "hubs", strconv.Itoa(hubID), "pause"), nil, hub)
+	return hub, err
+}
+
+// ResumeHub resumes a paused live game session
+func (c *Client) ResumeHub(ctx context.Context, gameID string, hubID int) (*Hub, error) {
+	hub := &Hub{}
+	_, err := c.do(ctx, http.MethodPost, escapePath("v1", "admin", "games", gameID, "hubs", strconv.Itoa(hubID), "resume"), nil, hub)
+	return hub, err
+}
+
+// KickClient disconnects a user's clients from a live game session
+func (c *Client) KickClient(ctx context.Context, gameID string, hubID int, userID string) error {
+	_, err := c.do(ctx, http.MethodDelete, escapePath("v1", "admin", "games", gameID, "hubs", strconv.Itoa(hubID), "clients", userID), nil, nil)
+	return err
+}
+
+// BroadcastMessage sends a system message to the players connected to a live game session
+// It returns how many players the message was sent to
+func (c *Client) BroadcastMessage(ctx context.Context, gameID string, hubID int, message string) (int, error) {
+	result := struct {
+		Recipients int `json:"recipients"`
+	}{}
+	request := struct {
+		Message string `json:"message"`
+	}{message}
+	_, err := c.do(ctx, http.MethodPost, escapePath("v1", "admin", "games", gameID, "hubs", strconv.Itoa(hubID), "messages"), request, &result)
+	return result.Recipients, err
+}
+
+// TerminateHub ends a live game session, saving it for its players first if save is set
+func (c *Client) TerminateHub(ctx context.Context, gameID string, hubID int, save bool) error {
+	path := escapePath("v1", "admin", "games", gameID, "hubs", strconv.Itoa(hubID))
+	if !save {
+		path += "?save=false"
+	}
+	_, err := c.do(ctx, http.MethodDelete, path, nil, nil)
+	return err
+}
+
+// escapePath returns the path made of the escaped segments
+func escapePath(segments ...string) string {
+	escaped := make([]string, len(segments))
+	for i, segment := range segments {
+		escaped[i] = url.PathEscape(segment)
+	}
+	return "/" + strings.Join(escaped, "/")
+}
+
+// do sends a request to the path and decodes the response into out
+// The body is encoded as JSON unless it is nil or an upload, and a *[]byte out receives the response body as is
+func (c *Client) do(ctx context.Context, method string, path string, in interface{}, out interface{}) (int, error) {
+
+	var body io.Reader
+	contentType := "application/json"
+	if raw, ok := in.(upload); ok {
+		body = bytes.NewReader(raw.data)
+		contentType = raw.contentType
+	} else if in != nil {
+		encoded, err := json.Marshal(in)
+		if err != nil {
+			return 0, err
+		}
+		body = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return resp.StatusCode, decodeError(resp)
+	}
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode, nil
+	}
+	if raw, ok := out.(*[]byte); ok {
+		*raw, err = io.ReadAll(resp.Body)
+		return resp.StatusCode, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp.StatusCode, fmt.Errorf("decoding %s %s response: %w", method, req.URL.Path, err)
+	}
+	return resp.StatusCode, nil
+}
+
+// decodeError reads the error body of a failed response
+func decodeError(resp *http.Response) error {
+	apiErr := &Error{Status: resp.StatusCode}
+	body := struct {
+		Error *Error `json:"error"`
+	}{Error: apiErr}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || apiErr.Code == "" {
+		apiErr.Code = "unknown"
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return apiErr
+}
@@ -0,0 +1,16 @@
+// Command game-sharing runs the Game Sharing Service with the games built into it
+package main
+
+import (
+	"os"
+
+	"github.com/game-sharing/service/games/newgame"
+	"github.com/game-sharing/service/platform"
+)
+
+func main() {
+	// The games served, under their IDs in the catalogue
+	platform.RegisterGame("0", &newgame.NewGameServer{})
+
+	os.Exit(platform.Run(os.Args[1:]))
+}
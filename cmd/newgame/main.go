@@ -0,0 +1,15 @@
+// Command newgame runs the demo game as a separate process, started by the platform when configured under games.<id>.process
+package main
+
+import (
+	"log"
+
+	"github.com/game-sharing/service/games/newgame"
+	"github.com/game-sharing/service/sdk"
+)
+
+func main() {
+	if err := sdk.Serve(&newgame.NewGameServer{}); err != nil {
+		log.Fatal(err)
+	}
+}
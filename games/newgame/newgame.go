@@ -0,0 +1,116 @@
+// Package newgame is the demo game, a sprite moved along a row of cells with the arrow keys
+package newgame
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/game-sharing/service/sdk"
+)
+
+// These are to check that the implementation of interfaces is correct
+var _ sdk.Game = (*NewGameServer)(nil)
+var _ sdk.GameState = (*NewGameState)(nil)
+var _ sdk.PreviewRenderer = (*NewGameServer)(nil)
+var _ sdk.Versioned = (*NewGameServer)(nil)
+
+// Size in pixels of each cell of the display in a preview
+const newGamePreviewCellSize = 16
+
+// NewGameServer is a concrete instance of sdk.Game
+type NewGameServer struct{}
+
+// NewGameState is a concrete instance of sdk.GameState
+type NewGameState struct {
+	spritePosition int
+	displayData    sdk.DisplayData
+}
+
+// newGameStateModel is how a NewGameState is saved
+// The JSON names match states saved before codecs were introduced, so they keep loading. Binary
+// field numbers 1, 2 and 5 held the state ID, server ID and saved date, and must not be reused.
+type newGameStateModel struct {
+	SpritePosition int    `json:"spritePosition" binary:"3"`
+	DisplayData    []byte `json:"displayData" binary:"4"`
+}
+
+// NewState returns the state of a new game, with the sprite in the middle
+func (server *NewGameServer) NewState() sdk.GameState {
+	return &NewGameState{
+		spritePosition: 3,
+		displayData:    make([]byte, 8),
+	}
+}
+
+// GameVersion returns the release of the demo game
+func (server *NewGameServer) GameVersion() string {
+	return "1"
+}
+
+// StateCodec saves the game's states with the compact binary codec
+func (server *NewGameServer) StateCodec() sdk.StateCodec {
+	return sdk.BinaryCodec
+}
+
+// ProcessState updates the GameState along with new DisplayData based on InputData
+func (server *NewGameServer) ProcessState(state sdk.GameState, inputs sdk.InputData) {
+	newState := state.(*NewGameState)
+
+	for _, char := range inputs {
+		// vbKeyLeft   37  LEFT ARROW key
+		// vbKeyUp     38  UP ARROW key
+		// vbKeyRight  39  RIGHT ARROW key
+		// vbKeyDown   40  DOWN ARROW key
+		if char == 37 && newState.spritePosition > 0 {
+			// LEFT ARROW key
+			newState.spritePosition--
+		} else if char == 39 && newState.spritePosition < 7 {
+			newState.spritePosition++
+		}
+	}
+
+	newState.displayData = []byte{48, 48, 48, 48, 48, 48, 48, 48}
+	newState.displayData[newState.spritePosition] = 49
+}
+
+// RenderPreview draws the row of cells in the display data, lighting up the one with the sprite
+func (server *NewGameServer) RenderPreview(display sdk.DisplayData) (image.Image, error) {
+	size := newGamePreviewCellSize
+	preview := image.NewRGBA(image.Rect(0, 0, len(display)*size, size))
+	draw.Draw(preview, preview.Bounds(), &image.Uniform{color.RGBA{32, 32, 32, 255}}, image.Point{}, draw.Src)
+
+	sprite := &image.Uniform{color.RGBA{240, 200, 40, 255}}
+	for i, cell := range display {
+		// Cells hold the characters '0' and '1'
+		if cell == 49 {
+			draw.Draw(preview, image.Rect(i*size+2, 2, (i+1)*size-2, size-2), sprite, image.Point{}, draw.Src)
+		}
+	}
+	return preview, nil
+}
+
+// GetDisplayData returns the current display data for the state
+func (state *NewGameState) GetDisplayData() sdk.DisplayData {
+	return state.displayData
+}
+
+// MarshalState returns NewGameState encoded with the codec
+func (state *NewGameState) MarshalState(codec sdk.StateCodec) ([]byte, error) {
+	return codec.Marshal(&newGameStateModel{
+		SpritePosition: state.spritePosition,
+		DisplayData:    state.displayData,
+	})
+}
+
+// UnmarshalState sets NewGameState from a model encoded with the codec
+func (state *NewGameState) UnmarshalState(codec sdk.StateCodec, data []byte) error {
+	model := &newGameStateModel{}
+	if err := codec.Unmarshal(data, model); err != nil {
+		return err
+	}
+	state.spritePosition = model.SpritePosition
+	state.displayData = model.DisplayData
+
+	return nil
+}
@@ -0,0 +1,351 @@
+package platform
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// "Table" Descriptions:
+// UserStateEntries stores the state models of each user for a specific game, by state ID
+// UserStateIndex stores the state IDs of each user for a specific game, ordered for listing
+// UserStates stores the legacy JSON list of states of each user, migrated at startup and on first use
+// SavedStates stores the encoded game state for each saved state of a game, with the versions it was saved with
+// StatePreviews stores the PNG preview of each saved state of a game that has one
+// StateOwners stores the users that have each saved state in their list
+// NewestStateID stores the next state ID to hand out for a game
+// Checkpoints stores the latest checkpoint of each live game session
+// Catalogue stores the entry of each game in the catalogue, by game ID
+// GameThumbnails stores the uploaded thumbnail of each game and its content type
+
+// NewPool returns a pool of connections to Redis
+func NewPool(config RedisConfig) *redis.Pool {
+	return &redis.Pool{
+		// Max number of idle connections in the pool.
+		MaxIdle: config.MaxIdle,
+		// Max number of connections in the pool, 0 for no limit.
+		MaxActive: config.MaxActive,
+		// Idle connections are closed after this long, 0 to keep them.
+		IdleTimeout: config.IdleTimeout,
+		// Dial is an application supplied function for creating and
+		// configuring a connection.
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", config.Addr, redis.DialConnectTimeout(config.ConnectTimeout))
+		},
+	}
+}
+
+// Ping tests connectivity for redis (PONG should be returned)
+func Ping(c redis.Conn) error {
+	// Send PING command to Redis
+	pong, err := doCommand(c, "PING")
+	if err != nil {
+		return err
+	}
+
+	// PING command returns a Redis "Simple String"
+	// Use redis.String to convert the interface type to string
+	s, err := redis.String(pong, err)
+	if err != nil {
+		return err
+	}
+	Logger.Debug("Redis responded to ping", "response", s)
+
+	return nil
+}
+
+func getSavedStatesObjectPrefix(gameID GameID, stateID StateID) string {
+	return "table: SavedStates, gameID: " + gameID + ", stateID: " + strconv.Itoa(stateID)
+}
+
+func getStatePreviewsObjectPrefix(gameID GameID, stateID StateID) string {
+	return "table: StatePreviews, gameID: " + gameID + ", stateID: " + strconv.Itoa(stateID)
+}
+
+func getNewestStateIDObjectPrefix(gameID GameID) string {
+	return "table: NewestStateID, gameID: " + gameID
+}
+
+// errStateAlreadySaved is returned when a state ID is already used by a saved state
+var errStateAlreadySaved = errors.New("state ID already exists in the database")
+
+// raiseNewestStateIDScript only ever moves the stored state ID counter forward
+var raiseNewestStateIDScript = redis.NewScript(1, `
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+if tonumber(ARGV[1]) > current then
+	redis.call("SET", KEYS[1], ARGV[1])
+end
+return 0
+`)
+
+// SaveStateBlob stores an encoded game state, failing if the state ID is already saved
+func SaveStateBlob(gameID GameID, stateID StateID, blob []byte) error {
+	conn := DatabasePool.Get()
+	defer conn.Close()
+
+	key := getSavedStatesObjectPrefix(gameID, stateID)
+
+	// Only set the key if it does not exist yet
+	reply, err := doCommand(conn, "SET", key, blob, "NX")
+	if err != nil {
+		return err
+	}
+	if reply == nil {
+		return errStateAlreadySaved
+	}
+
+	return nil
+}
+
+// LoadStateBlob returns an encoded game state, or redis.ErrNil if it was never saved
+func LoadStateBlob(gameID GameID, stateID StateID) ([]byte, error) {
+	conn := DatabasePool.Get()
+	defer conn.Close()
+
+	key := getSavedStatesObjectPrefix(gameID, stateID)
+
+	return redis.Bytes(doCommand(conn, "GET", key))
+}
+
+// replaceStateBlobScript overwrites a saved state only if it still holds what was read
+var replaceStateBlobScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[2])
+return 1
+`)
+
+// ReplaceStateBlob overwrites an encoded game state, returning false if it changed since old was read
+func ReplaceStateBlob(gameID GameID, stateID StateID, old []byte, blob []byte) (bool, error) {
+	conn := DatabasePool.Get()
+	defer conn.Close()
+
+	key := getSavedStatesObjectPrefix(gameID, stateID)
+
+	start := time.Now()
+	replaced, err := redis.Bool(replaceStateBlobScript.Do(conn, key, old, blob))
+	redisDurationHistogram.WithLabelValues("EVALSHA").Observe(time.Since(start).Seconds())
+
+	return replaced, err
+}
+
+// GetSavedStateIDs returns the IDs of every state saved for a game, in no particular order
+func GetSavedStateIDs(gameID GameID) ([]StateID, error) {
+	conn := DatabasePool.Get()
+	defer conn.Close()
+
+	prefix := "table: SavedStates, gameID: " + gameID + ", stateID: "
+
+	stateIDs := []StateID{}
+	cursor := "0"
+	for {
+		reply, err := redis.Values(doCommand(conn, "SCAN", cursor, "MATCH", prefix+"*", "COUNT", 100))
+		if err != nil {
+			return nil, err
+		}
+		keys, err := redis.Strings(reply[1], nil)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range keys {
+			if stateID, err := strconv.Atoi(strings.TrimPrefix(key, prefix)); err == nil {
+				stateIDs = append(stateIDs, stateID)
+			}
+		}
+
+		cursor, err = redis.String(reply[0], nil)
+		if err != nil || cursor == "0" {
+			return stateIDs, err
+		}
+	}
+}
+
+// SaveStatePreview stores the PNG preview of a saved state
+func SaveStatePreview(gameID GameID, stateID StateID, preview []byte) error {
+	conn := DatabasePool.Get()
+	defer conn.Close()
+
+	key := getStatePreviewsObjectPrefix(gameID, stateID)
+
+	_, err := doCommand(conn, "SET", key, preview)
+	return err
+}
+
+// LoadStatePreview returns the PNG preview of a saved state, or redis.ErrNil if it has none
+func LoadStatePreview(gameID GameID, stateID StateID) ([]byte, error) {
+	conn := DatabasePool.Get()
+	defer conn.Close()
+
+	key := getStatePreviewsObjectPrefix(gameID, stateID)
+
+	return redis.Bytes(doCommand(conn, "GET", key))
+}
+
+// GetNewestStateID returns the next state ID to hand out for a game
+func GetNewestStateID(gameID GameID) (StateID, error) {
+	conn := DatabasePool.Get()
+	defer conn.Close()
+
+	key := getNewestStateIDObjectPrefix(gameID)
+
+	stateID, err := redis.Int(doCommand(conn, "GET", key))
+	if err == redis.ErrNil {
+		// Nothing has been saved for this game yet
+		return 0, nil
+	}
+
+	return stateID, err
+}
+
+// RaiseNewestStateID records that state IDs below newestStateID are in use
+func RaiseNewestStateID(gameID GameID, newestStateID StateID) error {
+	conn := DatabasePool.Get()
+	defer conn.Close()
+
+	key := getNewestStateIDObjectPrefix(gameID)
+
+	start := time.Now()
+	_, err := raiseNewestStateIDScript.Do(conn, key, newestStateID)
+	redisDurationHistogram.WithLabelValues("EVALSHA").Observe(time.Since(start).Seconds())
+
+	return err
+}
+
+const checkpointsObjectPrefix = "table: Checkpoints"
+
+func getCheckpointField(gameID GameID, stateID StateID) string {
+	return "gameID: " + gameID + ", stateID: " + strconv.Itoa(stateID)
+}
+
+// SaveCheckpoint stores the latest checkpoint of a live game session
+func SaveCheckpoint(checkpoint *Checkpoint) error {
+	conn := DatabasePool.Get()
+	defer conn.Close()
+
+	jsonValue, encodeErr := json.Marshal(checkpoint)
+	if encodeErr != nil {
+		return encodeErr
+	}
+
+	field := getCheckpointField(checkpoint.GameID, checkpoint.StateID)
+	_, writeErr := doCommand(conn, "HSET", checkpointsObjectPrefix, field, jsonValue)
+
+	return writeErr
+}
+
+// DeleteCheckpoint removes the checkpoint of a live game session that has ended
+func DeleteCheckpoint(gameID GameID, stateID StateID) error {
+	conn := DatabasePool.Get()
+	defer conn.Close()
+
+	field := getCheckpointField(gameID, stateID)
+	_, err := doCommand(conn, "HDEL", checkpointsObjectPrefix, field)
+
+	return err
+}
+
+// GetCheckpoints returns the checkpoints of every live game session
+func GetCheckpoints() ([]Checkpoint, error) {
+	conn := DatabasePool.Get()
+	defer conn.Close()
+
+	storedValues, readErr := redis.ByteSlices(doCommand(conn, "HVALS", checkpointsObjectPrefix))
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	checkpoints := []Checkpoint{}
+	for _, storedValue := range storedValues {
+		checkpoint := Checkpoint{}
+		if decodeErr := json.Unmarshal(storedValue, &checkpoint); decodeErr != nil {
+			return nil, decodeErr
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+
+	return checkpoints, nil
+}
+
+const catalogueObjectPrefix = "table: Catalogue"
+
+func getGameThumbnailsObjectPrefix(gameID GameID) string {
+	return "table: GameThumbnails, gameID: " + gameID
+}
+
+// SaveCatalogueEntry stores a game in the catalogue, replacing any entry with its ID
+func SaveCatalogueEntry(game *Game) error {
+	conn := DatabasePool.Get()
+	defer conn.Close()
+
+	jsonValue, encodeErr := json.Marshal(game)
+	if encodeErr != nil {
+		return encodeErr
+	}
+
+	_, err := doCommand(conn, "HSET", catalogueObjectPrefix, game.ID, jsonValue)
+	return err
+}
+
+// DeleteCatalogueEntry removes a game and its thumbnail from the catalogue
+func DeleteCatalogueEntry(gameID GameID) error {
+	conn := DatabasePool.Get()
+	defer conn.Close()
+
+	conn.Send("MULTI")
+	conn.Send("HDEL", catalogueObjectPrefix, gameID)
+	conn.Send("DEL", getGameThumbnailsObjectPrefix(gameID))
+	_, err := doCommand(conn, "EXEC")
+	return err
+}
+
+// LoadCatalogueEntries returns every game in the catalogue
+func LoadCatalogueEntries() ([]Game, error) {
+	conn := DatabasePool.Get()
+	defer conn.Close()
+
+	storedValues, readErr := redis.ByteSlices(doCommand(conn, "HVALS", catalogueObjectPrefix))
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	games := []Game{}
+	for _, storedValue := range storedValues {
+		game := Game{}
+		if decodeErr := json.Unmarshal(storedValue, &game); decodeErr != nil {
+			return nil, decodeErr
+		}
+		games = append(games, game)
+	}
+
+	return games, nil
+}
+
+// SaveGameThumbnail stores the thumbnail of a game, replacing any previous one
+func SaveGameThumbnail(gameID GameID, contentType string, image []byte) error {
+	conn := DatabasePool.Get()
+	defer conn.Close()
+
+	_, err := doCommand(conn, "HSET", getGameThumbnailsObjectPrefix(gameID), "contentType", contentType, "image", image)
+	return err
+}
+
+// LoadGameThumbnail returns the thumbnail of a game and its content type, or redis.ErrNil if none was uploaded
+func LoadGameThumbnail(gameID GameID) (string, []byte, error) {
+	conn := DatabasePool.Get()
+	defer conn.Close()
+
+	values, err := redis.ByteSlices(doCommand(conn, "HMGET", getGameThumbnailsObjectPrefix(gameID), "contentType", "image"))
+	if err != nil {
+		return "", nil, err
+	}
+	if values[0] == nil || values[1] == nil {
+		return "", nil, redis.ErrNil
+	}
+	return string(values[0]), values[1], nil
+}
@@ -0,0 +1,455 @@
+package platform
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+)
+
+// GameID is the identifier for each game
+type GameID = string
+
+// UserID is the identifier for each user
+type UserID = string
+
+// GameServerMap maps each game ID to its respective server
+var GameServerMap map[GameID]GameServer
+
+// Users is a set of existing users
+var Users map[UserID]bool
+
+// usersMux guards Users, which is written by logins while other requests read it
+var usersMux sync.RWMutex
+
+// Hubs is a map of live game sessions
+var Hubs map[HubKey]*Hub
+
+// UserClients is a map of users to their clients
+var UserClients map[UserID]*Client
+
+// userClientsMux guards UserClients, which is changed as clients connect and disconnect
+var userClientsMux sync.Mutex
+
+// UserExists checks if the user has logged in
+func UserExists(userID UserID) bool {
+	usersMux.RLock()
+	defer usersMux.RUnlock()
+
+	return Users[userID]
+}
+
+// setUserClient makes the client the user's current one, returning the client it replaces
+func setUserClient(client *Client) (*Client, bool) {
+	userClientsMux.Lock()
+	defer userClientsMux.Unlock()
+
+	existing, ok := UserClients[client.userID]
+	UserClients[client.userID] = client
+	return existing, ok
+}
+
+// removeUserClient forgets the client, unless the user has connected another one since
+func removeUserClient(client *Client) {
+	userClientsMux.Lock()
+	defer userClientsMux.Unlock()
+
+	if UserClients[client.userID] == client {
+		delete(UserClients, client.userID)
+	}
+}
+
+// AddUser creates the user, returning false if it already existed
+func AddUser(userID UserID) bool {
+	usersMux.Lock()
+	defer usersMux.Unlock()
+
+	if Users[userID] {
+		return false
+	}
+	Users[userID] = true
+	return true
+}
+
+// RegisterLegacyRoutes adds the unversioned REST routes to the router
+// They take the user ID from the path and never authenticate it, so they are only served when enabled
+func RegisterLegacyRoutes(router *mux.Router) {
+	router.HandleFunc("/games/{id}/{userID}", GetStates).Methods("GET")
+	router.HandleFunc("/games/{id}/{userID}", CreateState).Methods("PUT")
+	router.HandleFunc("/games/{id}/{userID}/{stateID}", LoadState).Methods("GET")
+	router.HandleFunc("/games/{id}/{userID}/{stateID}", SaveState).Methods("PUT")
+	router.HandleFunc("/games/{id}/{userID}/{stateID}/stats", GetLatencyStats).Methods("GET")
+	router.HandleFunc("/games/{id}/{userID}/{stateID}/preview", GetStatePreview).Methods("GET")
+	router.HandleFunc("/login/{id}", Login).Methods("POST")
+}
+
+func errorCheck(w http.ResponseWriter, r *http.Request, gameID GameID, userID UserID) bool {
+	if !gameExists(w, gameID) {
+		return false
+	}
+
+	if !UserExists(userID) {
+		writeError(w, http.StatusNotFound, CodeUserNotFound, "User ID does not exist.")
+		return false
+	}
+
+	return true
+}
+
+// gameExists checks if the game ID is in the catalogue
+func gameExists(w http.ResponseWriter, gameID GameID) bool {
+	if _, ok := GameServerMap[gameID]; !ok || !gameEnabled(gameID) {
+		writeError(w, http.StatusNotFound, CodeGameNotFound, "Game ID does not exist.")
+		return false
+	}
+
+	return true
+}
+
+// getValidStateID returns a state ID if valid, otherwise -1
+func getValidStateID(w http.ResponseWriter, r *http.Request, stateIDStr string) StateID {
+	stateID, err := strconv.Atoi(stateIDStr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidStateID, "Invalid game session ID.")
+		return -1
+	}
+	return stateID
+}
+
+// isValidLiveSession checks if the state ID given is a valid live game session of the game
+func isValidLiveSession(w http.ResponseWriter, r *http.Request, gameID GameID, stateID StateID) bool {
+	if _, ok := GetHub(gameID, stateID); !ok {
+		writeError(w, http.StatusNotFound, CodeNotLiveSession, "State ID is not a valid live game session.")
+		return false
+	}
+
+	return true
+}
+
+// isLiveSessionMember checks that the user has played in the live game session or been invited to
+func isLiveSessionMember(w http.ResponseWriter, r *http.Request, gameID GameID, stateID StateID, userID UserID) bool {
+	if hub, ok := GetHub(gameID, stateID); ok && !hub.IsMember(userID) {
+		writeError(w, http.StatusForbidden, CodeNotSessionPlayer, "The user has not been invited to the live game session.")
+		return false
+	}
+
+	return true
+}
+
+// writeJSON replies to the request with a JSON body
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// GetGames returns an index of available games, optionally searched and filtered
+func GetGames(w http.ResponseWriter, r *http.Request) {
+	filter, message := parseGameFilter(r)
+	if message != "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, message)
+		return
+	}
+	writeJSON(w, http.StatusOK, CatalogueGames(filter))
+}
+
+// parseGameFilter reads the search and filters from the query string, returning a message describing the first invalid one
+func parseGameFilter(r *http.Request) (GameFilter, string) {
+	values := r.URL.Query()
+	filter := GameFilter{
+		Search: strings.TrimSpace(values.Get("q")),
+		Genre:  strings.TrimSpace(values.Get("genre")),
+	}
+
+	if raw := values.Get("players"); raw != "" {
+		players, err := strconv.Atoi(raw)
+		if err != nil || players < 1 {
+			return filter, "The players must be a positive number."
+		}
+		filter.Players = players
+	}
+
+	return filter, ""
+}
+
+// GetStates returns an index of saved states for a user in a specific game
+func GetStates(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	gameID := params["id"]
+	userID := params["userID"]
+
+	if !errorCheck(w, r, gameID, userID) {
+		return
+	}
+
+	writeUserStates(w, r, gameID, userID)
+}
+
+// writeUserStates replies with the user's saved states for a game
+func writeUserStates(w http.ResponseWriter, r *http.Request, gameID GameID, userID UserID) {
+	logger := annotateRequest(r, "game_id", gameID, "user_id", userID)
+	userStates, err := ListAllUserStates(gameID, userID)
+
+	if err != nil {
+		logger.Error("reading saved states failed", "error", err)
+		writeError(w, http.StatusInternalServerError, CodeDatabaseError, "Database error encountered while reading saved states.")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, userStates)
+}
+
+// CreateState starts a new game session and returns the new state ID
+func CreateState(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	gameID := params["id"]
+	userID := params["userID"]
+
+	if !errorCheck(w, r, gameID, userID) {
+		return
+	}
+
+	if hub, ok := startSession(w, r, gameID, userID); ok {
+		writeJSON(w, http.StatusOK, hubState(hub))
+	}
+}
+
+// startSession starts a new live game session owned by the user
+func startSession(w http.ResponseWriter, r *http.Request, gameID GameID, userID UserID) (*Hub, bool) {
+	release, ok := reserveNewHub(w, r, userID)
+	if !ok {
+		return nil, false
+	}
+	if !allowRequest(w, r, HubCreationLimiter, userID) {
+		release()
+		return nil, false
+	}
+
+	// Create a client and hub to handle the websocket connection
+	hub := NewHub(GameServerMap[gameID], userID)
+	added := addLiveSession(w, hub)
+	release()
+	if !added {
+		return nil, false
+	}
+	logger := annotateRequest(r, "game_id", gameID, "user_id", userID, "state_id", hub.state.GetID())
+	logger.Info("live game session created")
+
+	// Adds new state to user's list, the session is unusable without it
+	if err := AddToUserStates(gameID, userID, hubState(hub)); err != nil {
+		logger.Error("adding state to user's list failed", "error", err)
+		hub.Stop("Live game session could not be created.")
+		RemoveHub(hub.server.GetGameID(), hub.id)
+		writeError(w, http.StatusInternalServerError, CodeDatabaseError, "Database error encountered while creating the game session.")
+		return nil, false
+	}
+
+	return hub, true
+}
+
+// addLiveSession starts processing I/O on the hub and makes it available
+// The hub is stopped if another live game session already has its ID
+func addLiveSession(w http.ResponseWriter, hub *Hub) bool {
+	go hub.processIO()
+	if AddHub(hub) {
+		return true
+	}
+
+	hub.Stop("Live game session could not be created.")
+	hub.logger.Error("live game session ID is already in use")
+	writeError(w, http.StatusConflict, CodeStateConflict, "The live game session ID is already in use.")
+	return false
+}
+
+// hubState returns the state information of a live game session
+func hubState(hub *Hub) *State {
+	return &State{
+		ID:      strconv.Itoa(hub.state.GetID()),
+		SavedOn: hub.state.GetSavedDate(),
+	}
+}
+
+// LoadState loads a saved state as a live game session for a user
+func LoadState(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	gameID := params["id"]
+	userID := params["userID"]
+	stateIDStr := params["stateID"]
+
+	if !errorCheck(w, r, gameID, userID) {
+		return
+	}
+
+	stateID := getValidStateID(w, r, stateIDStr)
+	if stateID == -1 {
+		return
+	}
+
+	if hub, ok := loadSession(w, r, gameID, userID, stateID); ok {
+		writeJSON(w, http.StatusOK, hubState(hub))
+	}
+}
+
+// loadSession starts a live game session owned by the user from a saved state
+func loadSession(w http.ResponseWriter, r *http.Request, gameID GameID, userID UserID, stateID StateID) (*Hub, bool) {
+	logger := annotateRequest(r, "game_id", gameID, "user_id", userID, "state_id", stateID)
+
+	// State IDs are sequential, so only states in the user's own list can be loaded
+	if _, err := GetUserState(gameID, userID, stateID); err != nil {
+		writeUserStateError(w, r, err)
+		return nil, false
+	}
+
+	release, ok := reserveNewHub(w, r, userID)
+	if !ok {
+		return nil, false
+	}
+	if !allowRequest(w, r, HubCreationLimiter, userID) {
+		release()
+		return nil, false
+	}
+
+	// Create a client and hub to handle the websocket connection
+	hub, err := LoadHub(GameServerMap[gameID], stateID, userID)
+	if err != nil {
+		release()
+		logger.Warn("loading saved state failed", "error", err)
+		writeStateError(w, err)
+		return nil, false
+	}
+	added := addLiveSession(w, hub)
+	release()
+	if !added {
+		return nil, false
+	}
+	logger.Info("live game session loaded from saved state", "live_state_id", hub.id)
+
+	return hub, true
+}
+
+// SaveState saves the live games session as a saved state
+func SaveState(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	gameID := params["id"]
+	userID := params["userID"]
+	stateIDStr := params["stateID"]
+
+	if !errorCheck(w, r, gameID, userID) {
+		return
+	}
+
+	stateID := getValidStateID(w, r, stateIDStr)
+	if stateID == -1 || !isValidLiveSession(w, r, gameID, stateID) || !isLiveSessionMember(w, r, gameID, stateID, userID) {
+		return
+	}
+
+	if newState, ok := saveSession(w, r, gameID, userID, stateID); ok {
+		writeJSON(w, http.StatusOK, newState)
+	}
+}
+
+// saveSession saves a live game session as a new state in the user's list
+func saveSession(w http.ResponseWriter, r *http.Request, gameID GameID, userID UserID, stateID StateID) (*State, bool) {
+	logger := annotateRequest(r, "game_id", gameID, "user_id", userID, "state_id", stateID)
+
+	if !allowRequest(w, r, SaveLimiter, userID) {
+		return nil, false
+	}
+
+	hub, ok := GetHub(gameID, stateID)
+	if !ok {
+		writeStateError(w, ErrNotLiveSession)
+		return nil, false
+	}
+
+	// Save the state to the database, along with what the session showed at the saved moment
+	newStateID, snapshot, err := saveHub(hub)
+	if err != nil {
+		logger.Error("saving live game session failed", "error", err)
+		writeStateError(w, err)
+		return nil, false
+	}
+	logger.Info("live game session saved", "saved_state_id", newStateID)
+
+	// Return the state information to the client
+	newState := &State{
+		ID:      strconv.Itoa(newStateID),
+		SavedOn: snapshot.SavedOn,
+		Preview: captureStatePreview(logger, gameID, newStateID, snapshot.Display),
+	}
+
+	// Adds new state to user's list
+	if err := AddToUserStates(gameID, userID, newState); err != nil {
+		logger.Error("adding state to user's list failed", "error", err)
+		writeError(w, http.StatusInternalServerError, CodeDatabaseError, "Database error encountered while adding the saved state to the user's list.")
+		return nil, false
+	}
+
+	return newState, true
+}
+
+// GetLatencyStats returns the latency statistics of each player in a live game session
+func GetLatencyStats(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	gameID := params["id"]
+	userID := params["userID"]
+	stateIDStr := params["stateID"]
+
+	if !errorCheck(w, r, gameID, userID) {
+		return
+	}
+
+	stateID := getValidStateID(w, r, stateIDStr)
+	if stateID == -1 || !isValidLiveSession(w, r, gameID, stateID) || !isLiveSessionMember(w, r, gameID, stateID, userID) {
+		return
+	}
+	annotateRequest(r, "game_id", gameID, "user_id", userID, "state_id", stateID)
+
+	hub, _ := GetHub(gameID, stateID)
+	writeJSON(w, http.StatusOK, hub.LatencyStats())
+}
+
+// Login ensures that the userID exists
+func Login(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userID := params["id"]
+
+	logger := annotateRequest(r, "user_id", userID)
+
+	if !allowRequest(w, r, LoginLimiter, userID) {
+		return
+	}
+
+	if AddUser(userID) {
+		logger.Info("user created")
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleWebSocket processes a request for a WebSocket
+// i.e. connects a client to a game hub running a live game session
+func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	gameID := params["id"]
+	userID := params["userID"]
+	stateIDStr := params["stateID"]
+
+	if !errorCheck(w, r, gameID, userID) {
+		return
+	}
+
+	stateID := getValidStateID(w, r, stateIDStr)
+	if stateID == -1 || !isValidLiveSession(w, r, gameID, stateID) || !isLiveSessionMember(w, r, gameID, stateID, userID) {
+		return
+	}
+	annotateRequest(r, "game_id", gameID, "user_id", userID, "state_id", stateID)
+
+	ServeWebSocket(userID, gameID, stateID, w, r)
+}
@@ -0,0 +1,143 @@
+package platform
+
+import (
+	"fmt"
+
+	"github.com/game-sharing/service/sdk"
+	"github.com/gomodule/redigo/redis"
+)
+
+// ServerLogic provides the shared functionality between game servers
+// It runs a game from the SDK, which only implements its own state and logic
+type ServerLogic struct {
+	gameID        GameID
+	serverID      GameServerID
+	newestStateID SafeStateID
+	policy        BackpressurePolicy
+	game          sdk.Game
+}
+
+// NewServerLogic returns the game server running a game, registering the game's state migrations
+func NewServerLogic(gameID GameID, serverID GameServerID, game sdk.Game) *ServerLogic {
+	// Continue handing out state IDs from where the last run left off
+	newestStateID, err := GetNewestStateID(gameID)
+	if err != nil {
+		Logger.Error("reading newest state ID failed", "game_id", gameID, "error", err)
+	}
+
+	if migrator, ok := game.(sdk.StateMigrator); ok {
+		RegisterStateMigrations(gameID, migrator.StateMigrations()...)
+	}
+
+	return &ServerLogic{
+		gameID:        gameID,
+		serverID:      serverID,
+		newestStateID: SafeStateID{id: newestStateID},
+		policy:        *AppConfig.GameSettings(gameID).Backpressure,
+		game:          game,
+	}
+}
+
+// ProcessState runs one tick of the game
+func (server *ServerLogic) ProcessState(state sdk.GameState, inputs sdk.InputData) {
+	server.game.ProcessState(state, inputs)
+}
+
+// SaveAsState saves a live game session's state, encoded by its game loop, into the database as a new state
+func (server *ServerLogic) SaveAsState(stateModel []byte) (StateID, error) {
+	// Get a new id and insert it into the database
+	newStateID := server.newestStateID.GetAndIncrementSafeStateID()
+
+	// Saves the state in the database, where this new id should not exist yet
+	err := SaveStateBlob(server.gameID, newStateID, stateModel)
+	if err == errStateAlreadySaved {
+		return 0, ErrStateAlreadySaved
+	} else if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrDatabase, err)
+	}
+
+	// Make sure the id is not handed out again after a restart
+	if err := RaiseNewestStateID(server.gameID, newStateID+1); err != nil {
+		Logger.Error("recording newest state ID failed", "game_id", server.gameID, "error", err)
+	}
+	stateOperationsCounter.WithLabelValues(server.gameID, "save").Inc()
+
+	return newStateID, nil
+}
+
+// LoadState retrieves the GameState from the database
+func (server *ServerLogic) LoadState(stateID StateID) (*SessionState, error) {
+	savedState, err := LoadStateBlob(server.gameID, stateID)
+
+	if err == redis.ErrNil {
+		return nil, ErrStateNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabase, err)
+	}
+
+	loadedState, err := server.decodeState(stateID, savedState)
+	if err != nil {
+		return nil, err
+	}
+	stateOperationsCounter.WithLabelValues(server.gameID, "load").Inc()
+
+	return loadedState, nil
+}
+
+// RestoreCheckpoint decodes a live game session's checkpoint back into a GameState
+func (server *ServerLogic) RestoreCheckpoint(stateID StateID, data []byte) (*SessionState, error) {
+	return server.decodeState(stateID, data)
+}
+
+// NewState returns a new game in a live game session with a new state ID
+func (server *ServerLogic) NewState() *SessionState {
+	return &SessionState{
+		id:       server.NewStateID(),
+		serverID: server.serverID,
+		State:    server.game.NewState(),
+	}
+}
+
+// NewStateID returns the latest state ID
+func (server *ServerLogic) NewStateID() StateID {
+	return server.newestStateID.GetAndIncrementSafeStateID()
+}
+
+// GetGameID returns the ID of the game in the catalogue
+func (server *ServerLogic) GetGameID() GameID {
+	return server.gameID
+}
+
+// GetBackpressurePolicy returns how the game's hubs treat slow clients
+func (server *ServerLogic) GetBackpressurePolicy() BackpressurePolicy {
+	return server.policy
+}
+
+// GetStateCodec returns the codec the game's states are saved with
+func (server *ServerLogic) GetStateCodec() sdk.StateCodec {
+	return server.game.StateCodec()
+}
+
+// GetGame returns the game the server runs
+func (server *ServerLogic) GetGame() sdk.Game {
+	return server.game
+}
+
+// decodeState decodes a stored state into the game's state, migrating it from older schema versions first
+func (server *ServerLogic) decodeState(stateID StateID, data []byte) (*SessionState, error) {
+	versioned, codec, _, err := upgradeState(server.gameID, data)
+	if err != nil {
+		return nil, err
+	}
+
+	loadedState := server.game.NewState()
+	if err := loadedState.UnmarshalState(codec, versioned.State); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStateDecoding, err)
+	}
+	return &SessionState{
+		id:        stateID,
+		serverID:  server.serverID,
+		savedDate: versioned.SavedOn,
+		State:     loadedState,
+	}, nil
+}
@@ -0,0 +1,271 @@
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+// Limits on the system messages operators send to players
+const (
+	maxSystemMessageLength = 500
+	noticeBufferSize       = 8
+)
+
+// HubInfo is the model of a live game session shown to operators
+type HubInfo struct {
+	ID             StateID         `json:"id"`
+	GameID         GameID          `json:"gameID"`
+	Owner          UserID          `json:"owner"`
+	Players        []UserID        `json:"players"`
+	Clients        []LatencyReport `json:"clients"`
+	Paused         bool            `json:"paused"`
+	TickIntervalMs float64         `json:"tickIntervalMs"`
+	TicksPerSecond float64         `json:"ticksPerSecond"`
+	StartedOn      time.Time       `json:"startedOn"`
+	AgeSeconds     float64         `json:"ageSeconds"`
+	LastInputOn    *time.Time      `json:"lastInputOn"`
+}
+
+// SystemMessage is sent to players between frames to tell them about operator actions
+type SystemMessage struct {
+	MessageType string `json:"type"`
+	Message     string `json:"message,omitempty"`
+}
+
+// messageRequest is the body of a request to send players a system message
+type messageRequest struct {
+	Message string `json:"message"`
+}
+
+// Info describes the hub for operators
+func (hub *Hub) Info() HubInfo {
+	age := time.Since(hub.startedOn)
+	info := HubInfo{
+		ID:             hub.id,
+		GameID:         hub.server.GetGameID(),
+		Owner:          hub.owner,
+		Players:        hub.Players(),
+		Clients:        hub.LatencyStats(),
+		Paused:         hub.paused.Load(),
+		TickIntervalMs: float64(hub.tickInterval) / float64(time.Millisecond),
+		StartedOn:      hub.startedOn,
+		AgeSeconds:     age.Seconds(),
+	}
+	if age > 0 {
+		info.TicksPerSecond = float64(hub.ticks.Load()) / age.Seconds()
+	}
+	if lastInput := hub.lastInput.Load(); lastInput != 0 {
+		lastInputOn := time.Unix(0, lastInput)
+		info.LastInputOn = &lastInputOn
+	}
+	sort.Strings(info.Players)
+	return info
+}
+
+// SetPaused pauses or resumes the game and tells the connected players
+func (hub *Hub) SetPaused(paused bool) {
+	if hub.paused.Swap(paused) == paused {
+		return
+	}
+	notice := SystemMessage{MessageType: "resumed", Message: "The game was resumed by an operator."}
+	if paused {
+		notice = SystemMessage{MessageType: "paused", Message: "The game was paused by an operator."}
+	}
+	hub.Notify(notice)
+	hub.logger.Info("hub " + notice.MessageType + " by an operator")
+}
+
+// Notify sends a system message to every connected client, returning how many it was queued for
+// Clients whose notice buffer is full miss the message rather than holding up the hub
+func (hub *Hub) Notify(notice SystemMessage) int {
+	message, err := json.Marshal(notice)
+	if err != nil {
+		hub.logger.Error("encoding system message failed", "error", err)
+		return 0
+	}
+
+	recipients := 0
+	hub.run(func() {
+		for client := range hub.clients {
+			select {
+			case client.notices <- message:
+				recipients++
+			default:
+			}
+		}
+	})
+	return recipients
+}
+
+// Kick disconnects the user's clients with the reason, returning false if none were connected
+// The user remains a player of the hub, so they are still credited with its saves
+func (hub *Hub) Kick(userID UserID, reason string) bool {
+	kicked := false
+	hub.run(func() {
+		for client := range hub.clients {
+			if client.userID != userID {
+				continue
+			}
+			client.closeReason = reason
+			close(client.send)
+			delete(hub.clients, client)
+			removeUserClient(client)
+			connectedClientsGauge.WithLabelValues(hub.server.GetGameID()).Dec()
+			hub.logger.Info("client kicked by an operator", "user_id", client.userID, "conn_id", client.id)
+			kicked = true
+		}
+	})
+	return kicked
+}
+
+// ListHubs returns every live game session, optionally only those of one game
+func ListHubs(w http.ResponseWriter, r *http.Request) {
+	gameID := r.URL.Query().Get("gameID")
+
+	hubs := []HubInfo{}
+	for _, hub := range LiveHubs() {
+		if gameID == "" || hub.server.GetGameID() == gameID {
+			hubs = append(hubs, hub.Info())
+		}
+	}
+	sort.Slice(hubs, func(i, j int) bool {
+		return hubs[i].StartedOn.Before(hubs[j].StartedOn)
+	})
+	writeJSON(w, http.StatusOK, hubs)
+}
+
+// GetHubInfo returns a live game session
+func GetHubInfo(w http.ResponseWriter, r *http.Request) {
+	if hub, ok := adminHub(w, r); ok {
+		writeJSON(w, http.StatusOK, hub.Info())
+	}
+}
+
+// SaveHub saves a live game session for each of its players
+func SaveHub(w http.ResponseWriter, r *http.Request) {
+	hub, ok := adminHub(w, r)
+	if !ok {
+		return
+	}
+
+	newState, err := saveForPlayers(hub)
+	if newState == nil {
+		writeStateError(w, err)
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeDatabaseError, "Database error encountered while adding the saved state to the players' lists.")
+		return
+	}
+	requestLogger(r).Info("live game session saved by an operator", "saved_state_id", newState.ID)
+	writeJSON(w, http.StatusCreated, newState)
+}
+
+// PauseHub stops a live game session from ticking until it is resumed
+func PauseHub(w http.ResponseWriter, r *http.Request) {
+	if hub, ok := adminHub(w, r); ok {
+		hub.SetPaused(true)
+		writeJSON(w, http.StatusOK, hub.Info())
+	}
+}
+
+// ResumeHub restarts a paused live game session
+func ResumeHub(w http.ResponseWriter, r *http.Request) {
+	if hub, ok := adminHub(w, r); ok {
+		hub.SetPaused(false)
+		writeJSON(w, http.StatusOK, hub.Info())
+	}
+}
+
+// KickClient disconnects a player from a live game session
+func KickClient(w http.ResponseWriter, r *http.Request) {
+	hub, ok := adminHub(w, r)
+	if !ok {
+		return
+	}
+	userID := mux.Vars(r)["userID"]
+	annotateRequest(r, "user_id", userID)
+
+	if !hub.Kick(userID, "You were disconnected from the live game session by an operator.") {
+		writeError(w, http.StatusNotFound, CodeClientNotFound, "The user is not connected to the live game session.")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// BroadcastMessage sends a system message to the players connected to a live game session
+func BroadcastMessage(w http.ResponseWriter, r *http.Request) {
+	hub, ok := adminHub(w, r)
+	if !ok {
+		return
+	}
+
+	var request messageRequest
+	decoder := json.NewDecoder(io.LimitReader(r.Body, 16*1024))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "The request body must be a JSON object with a message.")
+		return
+	}
+	request.Message = strings.TrimSpace(request.Message)
+	if request.Message == "" || utf8.RuneCountInString(request.Message) > maxSystemMessageLength {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("The message must be 1 to %d characters.", maxSystemMessageLength))
+		return
+	}
+
+	recipients := hub.Notify(SystemMessage{MessageType: "system", Message: request.Message})
+	requestLogger(r).Info("system message sent by an operator", "recipients", recipients)
+	writeJSON(w, http.StatusOK, struct {
+		Recipients int `json:"recipients"`
+	}{recipients})
+}
+
+// TerminateHub ends a live game session, saving it for its players unless save is false
+func TerminateHub(w http.ResponseWriter, r *http.Request) {
+	hub, ok := adminHub(w, r)
+	if !ok {
+		return
+	}
+	save := r.URL.Query().Get("save") != "false"
+
+	ended := false
+	if save {
+		ended = hub.End("The live game session was ended by an operator, your game has been saved.")
+	} else if hub.ending.CompareAndSwap(false, true) {
+		hub.Stop("The live game session was ended by an operator.")
+		if err := DeleteCheckpoint(hub.server.GetGameID(), hub.id); err != nil {
+			hub.logger.Error("deleting checkpoint failed", "error", err)
+		}
+		RemoveHub(hub.server.GetGameID(), hub.id)
+		ended = true
+	}
+	if !ended {
+		writeError(w, http.StatusNotFound, CodeNotLiveSession, "The live game session is already ending.")
+		return
+	}
+	requestLogger(r).Info("live game session terminated by an operator", "saved", save)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// adminHub returns the live game session named in the path, replying with an error if there is none
+func adminHub(w http.ResponseWriter, r *http.Request) (*Hub, bool) {
+	params := mux.Vars(r)
+	stateID := getValidStateID(w, r, params["hubID"])
+	if stateID == -1 {
+		return nil, false
+	}
+	hub, ok := GetHub(params["gameID"], stateID)
+	if !ok {
+		writeError(w, http.StatusNotFound, CodeNotLiveSession, "State ID is not a valid live game session.")
+		return nil, false
+	}
+	annotateRequest(r, "game_id", hub.server.GetGameID(), "state_id", hub.id)
+	return hub, true
+}
@@ -0,0 +1,156 @@
+package platform
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// authToken is a bearer token issued to a user
+type authToken struct {
+	userID  UserID
+	expires time.Time
+}
+
+// tokens maps each bearer token to the user it was issued to
+var (
+	tokens    = make(map[string]authToken)
+	tokensMux sync.Mutex
+)
+
+// minSecretLength is the shortest secret a user can be created with
+const minSecretLength = 8
+
+// credentials maps each user created through the versioned API to a hash of its secret
+var (
+	credentials    = make(map[UserID][sha256.Size]byte)
+	credentialsMux sync.Mutex
+)
+
+// userKey is the context key of the user a request is authenticated as
+type userKey struct{}
+
+// InitAuth starts forgetting expired bearer tokens
+func InitAuth() {
+	go func() {
+		for range time.Tick(time.Minute) {
+			forgetExpiredTokens()
+		}
+	}()
+}
+
+// IssueToken returns a new bearer token for the user and when it expires
+func IssueToken(userID UserID) (string, time.Time) {
+	buf := make([]byte, 32)
+	rand.Read(buf)
+	token := hex.EncodeToString(buf)
+	expires := time.Now().Add(AppConfig.Auth.TokenTTL)
+
+	tokensMux.Lock()
+	tokens[token] = authToken{userID: userID, expires: expires}
+	tokensMux.Unlock()
+	return token, expires
+}
+
+// checkCredential creates the user with the secret if it does not exist, or checks the secret of an existing user
+// Users created without a secret, through the unversioned API, cannot be issued tokens
+func checkCredential(userID UserID, secret string) (created bool, ok bool) {
+	hash := sha256.Sum256([]byte(secret))
+
+	credentialsMux.Lock()
+	defer credentialsMux.Unlock()
+
+	if stored, exists := credentials[userID]; exists {
+		return false, subtle.ConstantTimeCompare(stored[:], hash[:]) == 1
+	}
+	if !AddUser(userID) {
+		return false, false
+	}
+	credentials[userID] = hash
+	return true, true
+}
+
+// authenticate returns the user a bearer token was issued to, if it has not expired
+func authenticate(token string) (UserID, bool) {
+	tokensMux.Lock()
+	defer tokensMux.Unlock()
+
+	issued, ok := tokens[token]
+	if !ok || time.Now().After(issued.expires) {
+		return "", false
+	}
+	return issued.userID, true
+}
+
+// forgetExpiredTokens removes the tokens that can no longer be used
+func forgetExpiredTokens() {
+	tokensMux.Lock()
+	defer tokensMux.Unlock()
+
+	now := time.Now()
+	for token, issued := range tokens {
+		if now.After(issued.expires) {
+			delete(tokens, token)
+		}
+	}
+}
+
+// bearerToken returns the token from the Authorization header
+// Browsers cannot set headers on WebSocket requests, so they pass it as the access_token query parameter
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return r.URL.Query().Get("access_token")
+}
+
+// RequireAuth rejects requests without a valid bearer token and records the user they are from
+func RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := authenticate(bearerToken(r))
+		if !ok || !UserExists(userID) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="game-sharing"`)
+			writeError(w, http.StatusUnauthorized, CodeUnauthorized, "A valid bearer token is required.")
+			return
+		}
+
+		annotateRequest(r, "user_id", userID)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, userID)))
+	})
+}
+
+// RequireAdmin rejects requests without one of the configured admin tokens
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isAdminToken(bearerToken(r)) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="game-sharing-admin"`)
+			writeError(w, http.StatusUnauthorized, CodeUnauthorized, "A valid admin token is required.")
+			return
+		}
+
+		annotateRequest(r, "admin", true)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isAdminToken checks the token against the configured admin tokens in constant time
+func isAdminToken(token string) bool {
+	matched := 0
+	for _, adminToken := range AppConfig.Auth.AdminTokens {
+		matched |= subtle.ConstantTimeCompare([]byte(token), []byte(adminToken))
+	}
+	return token != "" && matched == 1
+}
+
+// authenticatedUser returns the user a request passed through RequireAuth is from
+func authenticatedUser(r *http.Request) UserID {
+	userID, _ := r.Context().Value(userKey{}).(UserID)
+	return userID
+}
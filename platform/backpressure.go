@@ -0,0 +1,162 @@
+package platform
+
+import (
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/game-sharing/service/sdk"
+	"gopkg.in/yaml.v3"
+)
+
+// BackpressurePolicy decides how a hub treats clients that cannot keep up with the game loop
+type BackpressurePolicy struct {
+	// Fraction of the send buffer in use before a client is considered congested
+	CongestionThreshold float64 `yaml:"congestionThreshold"`
+
+	// Fraction of the send buffer in use below which a congested client can recover
+	RecoveryThreshold float64 `yaml:"recoveryThreshold"`
+
+	// Replace queued frames with the latest frame when the send buffer is full
+	CoalesceFrames bool `yaml:"coalesceFrames"`
+
+	// Largest number of frames a client's frame rate can be divided by
+	MaxFrameInterval int `yaml:"maxFrameInterval"`
+
+	// Uncongested deliveries needed before a client's frame rate is doubled again
+	RecoveryFrames int `yaml:"recoveryFrames"`
+
+	// How long a client can stay congested without making progress before it is dropped
+	MaxOverloadDuration time.Duration `yaml:"maxOverloadDuration"`
+}
+
+// DefaultBackpressurePolicy is used by games that do not define their own policy
+var DefaultBackpressurePolicy = BackpressurePolicy{
+	CongestionThreshold: 0.5,
+	RecoveryThreshold:   0.25,
+	CoalesceFrames:      true,
+	MaxFrameInterval:    8,
+	RecoveryFrames:      100,
+	MaxOverloadDuration: 10 * time.Second,
+}
+
+// UnmarshalYAML reads a game's policy, taking the settings it omits from DefaultBackpressurePolicy
+func (policy *BackpressurePolicy) UnmarshalYAML(node *yaml.Node) error {
+	// Decoding a node does not reject unknown settings as the rest of the config file does, so they are checked here
+	known := map[string]bool{}
+	policyType := reflect.TypeOf(*policy)
+	for i := 0; i < policyType.NumField(); i++ {
+		known[policyType.Field(i).Tag.Get("yaml")] = true
+	}
+	for i := 0; node.Kind == yaml.MappingNode && i < len(node.Content); i += 2 {
+		if key := node.Content[i]; !known[key.Value] {
+			return fmt.Errorf("line %d: unknown backpressure setting %q", key.Line, key.Value)
+		}
+	}
+
+	type plainPolicy BackpressurePolicy
+	merged := plainPolicy(DefaultBackpressurePolicy)
+	if err := node.Decode(&merged); err != nil {
+		return err
+	}
+	*policy = BackpressurePolicy(merged)
+	return nil
+}
+
+// frameDelivery is the per-client state of the backpressure policy
+// It is only accessed from the hub's processIO goroutine
+type frameDelivery struct {
+	// Only every frameInterval-th frame is sent to the client
+	frameInterval int
+
+	// Frames skipped since the last one sent
+	skippedFrames int
+
+	// Uncongested deliveries since the frame rate was last changed
+	healthyFrames int
+
+	// When the congested client last wrote a frame, zero if it is not congested
+	overloadedSince time.Time
+
+	// Frames written by the client as of overloadedSince
+	writtenAtOverload uint64
+}
+
+// deliverFrame sends a frame to a client according to the hub's backpressure policy
+// Returns false if the client has been overloaded for too long and should be dropped
+func (hub *Hub) deliverFrame(client *Client, frame sdk.DisplayData) bool {
+	policy := hub.policy
+	delivery := &client.delivery
+
+	// Skip frames to reduce the frame rate of slow clients
+	if delivery.frameInterval < 1 {
+		delivery.frameInterval = 1
+	}
+	delivery.skippedFrames++
+	if delivery.skippedFrames < delivery.frameInterval {
+		return true
+	}
+	delivery.skippedFrames = 0
+
+	select {
+	case client.send <- frame:
+	default:
+		if !policy.CoalesceFrames {
+			// Without coalescing, a full buffer simply drops this frame
+			break
+		}
+		// Drop the stale frames so the latest one takes their place
+		for drained := false; !drained; {
+			select {
+			case <-client.send:
+			default:
+				drained = true
+			}
+		}
+		select {
+		case client.send <- frame:
+		default:
+		}
+	}
+
+	usage := float64(len(client.send)) / float64(cap(client.send))
+	written := atomic.LoadUint64(&client.framesWritten)
+
+	if usage >= policy.CongestionThreshold {
+		// Congested: halve the client's frame rate and start the overload timer
+		delivery.healthyFrames = 0
+		if delivery.frameInterval < policy.MaxFrameInterval {
+			delivery.frameInterval *= 2
+			if delivery.frameInterval > policy.MaxFrameInterval {
+				delivery.frameInterval = policy.MaxFrameInterval
+			}
+		}
+		if delivery.overloadedSince.IsZero() {
+			delivery.overloadedSince = time.Now()
+			delivery.writtenAtOverload = written
+		}
+	} else if !delivery.overloadedSince.IsZero() {
+		// A client only recovers once it has drained its buffer itself
+		if usage <= policy.RecoveryThreshold && written > delivery.writtenAtOverload {
+			delivery.overloadedSince = time.Time{}
+		}
+	} else {
+		// Healthy: gradually restore the client's frame rate
+		delivery.healthyFrames++
+		if delivery.frameInterval > 1 && delivery.healthyFrames >= policy.RecoveryFrames {
+			delivery.frameInterval /= 2
+			delivery.healthyFrames = 0
+		}
+	}
+
+	// A congested client that is still writing frames is slow rather than stalled, so its timer restarts
+	if !delivery.overloadedSince.IsZero() && written > delivery.writtenAtOverload {
+		delivery.overloadedSince = time.Now()
+		delivery.writtenAtOverload = written
+	}
+
+	overloaded := !delivery.overloadedSince.IsZero() &&
+		time.Since(delivery.overloadedSince) > policy.MaxOverloadDuration
+	return !overloaded
+}
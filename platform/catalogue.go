@@ -0,0 +1,206 @@
+package platform
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Game is the model for a game in the catalogue
+type Game struct {
+	ID          GameID `json:"id"`
+	ImageNumber string `json:"imageID"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Genre       string `json:"genre,omitempty"`
+
+	// Number of players a live game session is meant for, 0 when not set
+	MinPlayers int `json:"minPlayers,omitempty"`
+	MaxPlayers int `json:"maxPlayers,omitempty"`
+
+	// How to play, e.g. which keys move the sprite
+	Controls string `json:"controls,omitempty"`
+	Version  string `json:"version,omitempty"`
+
+	// Disabled games are hidden from the catalogue and cannot be played
+	Enabled bool `json:"enabled"`
+
+	// Set when a thumbnail has been uploaded
+	Thumbnail bool      `json:"thumbnail,omitempty"`
+	UpdatedOn time.Time `json:"updatedOn"`
+}
+
+// GameFilter selects games from the catalogue, zero values match every game
+type GameFilter struct {
+	// Matched against the name and description, ignoring case
+	Search string
+	Genre  string
+
+	// Only games meant for this many players
+	Players int
+
+	IncludeDisabled bool
+}
+
+// DefaultGames are added to the catalogue when the store has none
+var DefaultGames = []Game{
+	{
+		ID:          "0",
+		ImageNumber: "0",
+		Name:        "New Game",
+		Description: "A new game to play!",
+		Genre:       "Demo",
+		MinPlayers:  1,
+		Controls:    "The left and right arrow keys move the sprite.",
+		Version:     "1",
+		Enabled:     true,
+	},
+}
+
+// catalogue caches the stored catalogue by game ID
+var (
+	catalogue    = make(map[GameID]Game)
+	catalogueMux sync.RWMutex
+)
+
+// LoadCatalogue reads the catalogue from the store, adding the default games if it is empty
+// The default games are used until the store can be read
+func LoadCatalogue() error {
+	catalogueMux.Lock()
+	defer catalogueMux.Unlock()
+
+	for _, game := range DefaultGames {
+		catalogue[game.ID] = game
+	}
+
+	storedGames, err := LoadCatalogueEntries()
+	if err != nil {
+		return err
+	}
+
+	if len(storedGames) == 0 {
+		for _, game := range DefaultGames {
+			game.UpdatedOn = time.Now()
+			if err := SaveCatalogueEntry(&game); err != nil {
+				return err
+			}
+			catalogue[game.ID] = game
+		}
+		return nil
+	}
+
+	catalogue = make(map[GameID]Game)
+	for _, game := range storedGames {
+		catalogue[game.ID] = game
+	}
+	return nil
+}
+
+// CatalogueGames returns the games matching the filter, ordered by name
+func CatalogueGames(filter GameFilter) []Game {
+	catalogueMux.RLock()
+	defer catalogueMux.RUnlock()
+
+	search := strings.ToLower(filter.Search)
+	games := []Game{}
+	for _, game := range catalogue {
+		if !game.Enabled && !filter.IncludeDisabled {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(game.Name), search) && !strings.Contains(strings.ToLower(game.Description), search) {
+			continue
+		}
+		if filter.Genre != "" && !strings.EqualFold(game.Genre, filter.Genre) {
+			continue
+		}
+		if filter.Players > 0 && (filter.Players < game.MinPlayers || (game.MaxPlayers > 0 && filter.Players > game.MaxPlayers)) {
+			continue
+		}
+		games = append(games, game)
+	}
+
+	sort.Slice(games, func(i, j int) bool {
+		if games[i].Name != games[j].Name {
+			return games[i].Name < games[j].Name
+		}
+		return games[i].ID < games[j].ID
+	})
+	return games
+}
+
+// CatalogueGame returns a game in the catalogue, whether or not it is enabled
+func CatalogueGame(gameID GameID) (Game, bool) {
+	catalogueMux.RLock()
+	defer catalogueMux.RUnlock()
+
+	game, ok := catalogue[gameID]
+	return game, ok
+}
+
+// gameEnabled checks if a game is in the catalogue and can be played
+func gameEnabled(gameID GameID) bool {
+	game, ok := CatalogueGame(gameID)
+	return ok && game.Enabled
+}
+
+// Errors returned when changing the catalogue
+var (
+	errGameExists         = errors.New("game ID is already in the catalogue")
+	errGameNotInCatalogue = errors.New("game ID is not in the catalogue")
+)
+
+// AddCatalogueGame stores a new game, then makes it visible in the catalogue
+func AddCatalogueGame(game Game) (Game, error) {
+	catalogueMux.Lock()
+	defer catalogueMux.Unlock()
+
+	if _, ok := catalogue[game.ID]; ok {
+		return game, errGameExists
+	}
+
+	game.UpdatedOn = time.Now()
+	if err := SaveCatalogueEntry(&game); err != nil {
+		return game, err
+	}
+	catalogue[game.ID] = game
+	return game, nil
+}
+
+// UpdateCatalogueGame changes a game in the store and the catalogue, unless update returns an error
+func UpdateCatalogueGame(gameID GameID, update func(*Game) error) (Game, error) {
+	catalogueMux.Lock()
+	defer catalogueMux.Unlock()
+
+	game, ok := catalogue[gameID]
+	if !ok {
+		return game, errGameNotInCatalogue
+	}
+	if err := update(&game); err != nil {
+		return game, err
+	}
+
+	game.ID = gameID
+	game.UpdatedOn = time.Now()
+	if err := SaveCatalogueEntry(&game); err != nil {
+		return game, err
+	}
+	catalogue[gameID] = game
+	return game, nil
+}
+
+// RemoveCatalogueGame deletes a game and its thumbnail from the store and the catalogue
+func RemoveCatalogueGame(gameID GameID) error {
+	catalogueMux.Lock()
+	defer catalogueMux.Unlock()
+
+	if _, ok := catalogue[gameID]; !ok {
+		return errGameNotInCatalogue
+	}
+	if err := DeleteCatalogueEntry(gameID); err != nil {
+		return err
+	}
+	delete(catalogue, gameID)
+	return nil
+}
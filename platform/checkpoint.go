@@ -0,0 +1,148 @@
+package platform
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/game-sharing/service/sdk"
+)
+
+// Checkpoint is the model for the latest snapshot of a live game session
+type Checkpoint struct {
+	GameID         GameID    `json:"gameID"`
+	StateID        StateID   `json:"stateID"`
+	Owner          UserID    `json:"owner"`
+	Players        []UserID  `json:"players"`
+	State          []byte    `json:"state"`
+	CheckpointedAt time.Time `json:"checkpointedAt"`
+}
+
+// checkpoint stores a snapshot of the hub's game state so it survives a crash
+// Must only be called from the game loop, or after the game loop has exited
+func (hub *Hub) checkpoint() {
+	gameID := hub.server.GetGameID()
+	stateID := hub.state.GetID()
+
+	// A zero saved date keeps the state a live session when it is restored
+	stateModel, err := encodeState(hub.server, hub.state.State, time.Time{})
+	if err != nil {
+		hub.logger.Error("encoding checkpoint failed", "error", err)
+		return
+	}
+
+	// Make sure the id is not handed out to another session after a restart
+	if !hub.checkpointed {
+		if err := RaiseNewestStateID(gameID, stateID+1); err != nil {
+			hub.logger.Error("recording newest state ID failed", "error", err)
+			return
+		}
+	}
+
+	err = SaveCheckpoint(&Checkpoint{
+		GameID:         gameID,
+		StateID:        stateID,
+		Owner:          hub.owner,
+		Players:        hub.Players(),
+		State:          stateModel,
+		CheckpointedAt: time.Now(),
+	})
+	if err != nil {
+		hub.logger.Error("storing checkpoint failed", "error", err)
+		return
+	}
+	hub.checkpointed = true
+	hub.logger.Debug("checkpoint stored")
+}
+
+// StateSnapshot is a live game session's state encoded for saving, along with what it displayed
+type StateSnapshot struct {
+	State   []byte
+	Display sdk.DisplayData
+	SavedOn time.Time
+	Err     error
+}
+
+// Snapshot encodes the hub's game state for saving between ticks of the game loop
+// Once the game loop has exited, the state no longer changes and is encoded directly
+func (hub *Hub) Snapshot() StateSnapshot {
+	reply := make(chan StateSnapshot, 1)
+	select {
+	case hub.snapshotRequest <- reply:
+		return <-reply
+	case <-hub.loopDone:
+		return hub.snapshot()
+	}
+}
+
+// snapshot encodes the game state for saving
+// Must only be called from the game loop, or after the game loop has exited
+func (hub *Hub) snapshot() StateSnapshot {
+	savedOn := time.Now()
+	stateModel, err := encodeState(hub.server, hub.state.State, savedOn)
+	if err != nil {
+		err = fmt.Errorf("%w: %v", ErrStateEncoding, err)
+	}
+	return StateSnapshot{State: stateModel, Display: hub.state.GetDisplayData(), SavedOn: savedOn, Err: err}
+}
+
+// saveHub saves the hub's game state as a new state, returning its ID with the snapshot that was saved
+func saveHub(hub *Hub) (StateID, StateSnapshot, error) {
+	snapshot := hub.Snapshot()
+	if snapshot.Err != nil {
+		return 0, snapshot, snapshot.Err
+	}
+	newStateID, err := hub.server.SaveAsState(snapshot.State)
+	return newStateID, snapshot, err
+}
+
+// requestCheckpoint asks the game loop to checkpoint at the end of the current tick
+func (hub *Hub) requestCheckpoint() {
+	select {
+	case hub.checkpointRequest <- struct{}{}:
+	default:
+		// A checkpoint is already pending
+	}
+}
+
+// RehydrateHubs restores every checkpointed game session as a live session
+func RehydrateHubs() error {
+	checkpoints, err := GetCheckpoints()
+	if err != nil {
+		return err
+	}
+
+	for _, checkpoint := range checkpoints {
+		logger := Logger.With("game_id", checkpoint.GameID, "state_id", checkpoint.StateID)
+
+		server, ok := GameServerMap[checkpoint.GameID]
+		if !ok {
+			logger.Warn("skipping checkpoint of a game that is not registered")
+			continue
+		}
+		if _, ok := GetHub(checkpoint.GameID, checkpoint.StateID); ok {
+			logger.Warn("skipping checkpoint of a live game session that is already running")
+			continue
+		}
+
+		state, err := server.RestoreCheckpoint(checkpoint.StateID, checkpoint.State)
+		if err != nil {
+			logger.Error("restoring checkpoint failed", "error", err)
+			continue
+		}
+
+		hub := startHub(server, state, checkpoint.Owner, checkpoint.Players...)
+		hub.checkpointed = true
+		go hub.processIO()
+		if !AddHub(hub) {
+			hub.Stop("Live game session could not be restored.")
+			logger.Error("live game session ID is already in use, not restoring its checkpoint")
+			continue
+		}
+
+		logger.Info("live game session restored from checkpoint",
+			"checkpointed_at", checkpoint.CheckpointedAt,
+			"players", checkpoint.Players)
+	}
+
+	return nil
+}
@@ -0,0 +1,250 @@
+package platform
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/game-sharing/service/sdk"
+	"github.com/gorilla/websocket"
+	"golang.org/x/time/rate"
+)
+
+// activeConnections counts the connections whose writers are still running
+var activeConnections sync.WaitGroup
+
+var upgrader websocket.Upgrader
+
+// InitWebSocket configures the upgrader from the WebSocket settings
+func InitWebSocket(config WebSocketConfig) {
+	upgrader = websocket.Upgrader{
+		ReadBufferSize:  config.ReadBufferSize,
+		WriteBufferSize: config.WriteBufferSize,
+		CheckOrigin:     checkWebSocketOrigin,
+	}
+}
+
+// pingPeriod returns the period to send pings to peer with. Must be less than pongWait.
+func pingPeriod() time.Duration {
+	return (AppConfig.WebSocket.PongWait * 9) / 10
+}
+
+// Client is a middleman between the websocket connection and the hub
+type Client struct {
+	// Represents the game that is currently active
+	hub *Hub
+
+	// The websocket connection.
+	conn *websocket.Conn
+
+	// Buffered channel of outbound messages.
+	send chan sdk.DisplayData
+
+	// Buffered channel of system messages, sent between frames
+	notices chan []byte
+
+	// The user playing through this client
+	userID UserID
+
+	// Identifies the connection in logs
+	id string
+
+	// Logger carrying the user, game, state and connection IDs
+	logger *slog.Logger
+
+	// Latency and jitter measurements for the connection
+	stats ClientStats
+
+	// Frame rate adaptation state, owned by the hub
+	delivery frameDelivery
+
+	// Number of frames written to the connection, accessed atomically
+	framesWritten uint64
+
+	// Reason sent to the player when the hub closes the connection
+	closeReason string
+
+	// Limits how fast the client's inputs are accepted, owned by the hub
+	inputLimiter *rate.Limiter
+}
+
+// readPump pumps messages from the websocket connection to the hub.
+//
+// The application runs readPump in a per-connection goroutine. The application
+// ensures that there is at most one reader on a connection by executing all
+// reads from this goroutine.
+func (c *Client) readPump() {
+	// Makes sure to close and unregister the client
+	defer func() {
+		c.hub.leave(c)
+		c.conn.Close()
+	}()
+
+	// Sets connection contraints
+	pongWait := AppConfig.WebSocket.PongWait
+	c.conn.SetReadLimit(AppConfig.WebSocket.MaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(appData string) error {
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		c.stats.recordPong(appData)
+		return nil
+	})
+
+	// Continuously reads messages until an error occurs
+	for {
+		_, message, err := c.conn.ReadMessage()
+
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				c.logger.Warn("websocket closed unexpectedly", "error", err)
+			}
+			break
+		}
+		c.stats.recordInput(time.Now())
+
+		// switch statement for game input (g), pause (p), unpause (u), save (s), saveas (a)
+		if !c.hub.sendInput(c, message) {
+			break
+		}
+	}
+}
+
+// writePump pumps messages from the hub to the websocket connection.
+//
+// A goroutine running writePump is started for each connection. The
+// application ensures that there is at most one writer to a connection by
+// executing all writes from this goroutine.
+func (c *Client) writePump() {
+	// Limit the ping period
+	ticker := time.NewTicker(pingPeriod())
+	statsTicker := time.NewTicker(AppConfig.WebSocket.StatsPeriod)
+	writeWait := AppConfig.WebSocket.WriteWait
+
+	// Ensure connection is closed and tickers are stopped
+	defer func() {
+		ticker.Stop()
+		statsTicker.Stop()
+		c.conn.Close()
+		activeConnections.Done()
+	}()
+
+	// Continuously writes messages
+	for {
+		select {
+		case message, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				// The hub closed the channel.
+				closeMessage := []byte{}
+				if c.closeReason != "" {
+					closeMessage = websocket.FormatCloseMessage(websocket.CloseGoingAway, c.closeReason)
+				}
+				c.conn.WriteMessage(websocket.CloseMessage, closeMessage)
+				return
+			}
+
+			// Skip the queued frames the player would never see
+			n := len(c.send)
+			written := 1
+			if c.hub.policy.CoalesceFrames {
+				for i := 0; i < n; i++ {
+					select {
+					case queued, ok := <-c.send:
+						if ok {
+							message = queued
+						}
+					default:
+					}
+				}
+			}
+
+			w, err := c.conn.NextWriter(websocket.TextMessage)
+			if err != nil {
+				return
+			}
+			w.Write(message)
+
+			// Add queued outputs to the current websocket message
+			if !c.hub.policy.CoalesceFrames {
+				for i := 0; i < n; i++ {
+					w.Write(<-c.send)
+				}
+				written += n
+			}
+
+			if err := w.Close(); err != nil {
+				return
+			}
+			c.stats.recordFrame(time.Now())
+			atomic.AddUint64(&c.framesWritten, uint64(written))
+		case notice := <-c.notices:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, notice); err != nil {
+				return
+			}
+		case <-statsTicker.C:
+			// Probe the round-trip time, then report the latest measurements
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, newPingPayload()); err != nil {
+				return
+			}
+			message, err := c.stats.statsMessage(c.userID)
+			if err != nil {
+				c.logger.Error("encoding stats message failed", "error", err)
+				continue
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+
+	}
+}
+
+// ServeWebSocket handles websocket requests from the peer.
+func ServeWebSocket(userID UserID, gameID GameID, stateID StateID, w http.ResponseWriter, r *http.Request) {
+	logger := requestLogger(r)
+	conn, err := upgrader.Upgrade(w, r, nil)
+
+	if err != nil {
+		logger.Warn("websocket upgrade failed", "error", err)
+		return
+	}
+
+	// Create a new client
+	hub, ok := GetHub(gameID, stateID)
+	if !ok {
+		logger.Info("live game session ended before the client joined")
+		conn.Close()
+		return
+	}
+	client := &Client{hub: hub, conn: conn, send: make(chan sdk.DisplayData, AppConfig.WebSocket.SendBufferSize), notices: make(chan []byte, noticeBufferSize), userID: userID, id: newCorrelationID()}
+	client.logger = logger.With("conn_id", client.id)
+	client.logger.Info("websocket connected")
+
+	// Unregister the user from existing hubs
+	if existingClient, existingUser := setUserClient(client); existingUser {
+		existingClient.hub.leave(existingClient)
+	}
+
+	// Register client to the correct hub
+	if !client.hub.join(client) {
+		removeUserClient(client)
+		client.logger.Info("live game session ended before the client joined")
+		conn.Close()
+		return
+	}
+
+	// Allow collection of memory referenced by the caller by doing all work in
+	// new goroutines.
+	activeConnections.Add(1)
+	go client.writePump()
+	go client.readPump()
+}
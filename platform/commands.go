@@ -0,0 +1,62 @@
+package platform
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// RunCommand runs a maintenance command named by the arguments after the flags, returning the exit code
+func RunCommand(args []string) int {
+	switch args[0] {
+	case "migrate-states":
+		return migrateStatesCommand(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q, the only command is migrate-states\n", args[0])
+		return 2
+	}
+}
+
+// migrateStatesCommand upgrades the saved states of every game, or of the game given, to their current schema versions
+func migrateStatesCommand(args []string) int {
+	flags := flag.NewFlagSet("migrate-states", flag.ContinueOnError)
+	gameID := flags.String("game", "", "Only migrate the saved states of this game")
+	dryRun := flags.Bool("dryRun", false, "Count the saved states that need migrating without changing them")
+	if err := flags.Parse(args); err == flag.ErrHelp {
+		return 0
+	} else if err != nil {
+		return 2
+	}
+
+	gameIDs := []GameID{}
+	for id := range GameServerMap {
+		if *gameID == "" || id == *gameID {
+			gameIDs = append(gameIDs, id)
+		}
+	}
+	if len(gameIDs) == 0 {
+		fmt.Fprintf(os.Stderr, "no game server is registered for game %q\n", *gameID)
+		return 2
+	}
+	sort.Strings(gameIDs)
+
+	exitCode := 0
+	for _, id := range gameIDs {
+		report, err := MigrateSavedStates(id, *dryRun)
+		Logger.Info("saved states migrated",
+			"game_id", id,
+			"schema_version", StateSchemaVersion(id),
+			"dry_run", *dryRun,
+			"scanned", report.Scanned,
+			"migrated", report.Migrated,
+			"failed", report.Failed)
+		if err != nil {
+			Logger.Error("migrating saved states stopped", "game_id", id, "error", err)
+			exitCode = 1
+		} else if report.Failed > 0 {
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
@@ -0,0 +1,65 @@
+package platform
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// Compressions of saved states
+const (
+	compressionNone = "none"
+	compressionGzip = "gzip"
+)
+
+// maxDecompressedStateSize bounds a compressed state once decompressed, so a small blob cannot exhaust memory
+const maxDecompressedStateSize = 64 << 20
+
+// compressState compresses an encoded state with the named compression
+func compressState(compression string, data []byte) ([]byte, error) {
+	switch compression {
+	case "", compressionNone:
+		return data, nil
+	case compressionGzip:
+		// It could not be decompressed again
+		if len(data) > maxDecompressedStateSize {
+			return nil, fmt.Errorf("state of %d bytes is larger than %d bytes", len(data), maxDecompressedStateSize)
+		}
+		var buffer bytes.Buffer
+		writer := gzip.NewWriter(&buffer)
+		if _, err := writer.Write(data); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unknown compression %q", compression)
+	}
+}
+
+// decompressState reverses compressState
+func decompressState(compression string, data []byte) ([]byte, error) {
+	switch compression {
+	case "", compressionNone:
+		return data, nil
+	case compressionGzip:
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		state, err := io.ReadAll(io.LimitReader(reader, maxDecompressedStateSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(state) > maxDecompressedStateSize {
+			return nil, fmt.Errorf("decompressed state is larger than %d bytes", maxDecompressedStateSize)
+		}
+		return state, nil
+	default:
+		return nil, fmt.Errorf("unknown compression %q", compression)
+	}
+}
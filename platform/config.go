@@ -0,0 +1,489 @@
+package platform
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the settings of the service
+// Settings are read from a YAML or JSON file, then environment variables, then flags
+type Config struct {
+	Server     ServerConfig          `yaml:"server"`
+	Redis      RedisConfig           `yaml:"redis"`
+	WebSocket  WebSocketConfig       `yaml:"websocket"`
+	Hubs       HubConfig             `yaml:"hubs"`
+	CORS       CORSConfig            `yaml:"cors"`
+	Auth       AuthConfig            `yaml:"auth"`
+	RateLimits RateLimitConfig       `yaml:"rateLimits"`
+	Logging    LoggingConfig         `yaml:"logging"`
+	Games      map[GameID]GameConfig `yaml:"games"`
+}
+
+// ServerConfig holds the networking settings
+type ServerConfig struct {
+	Addr              string        `yaml:"addr"`
+	WSAddr            string        `yaml:"wsAddr"`
+	Unified           bool          `yaml:"unified"`
+	TLSCert           string        `yaml:"tlsCert"`
+	TLSKey            string        `yaml:"tlsKey"`
+	ReadTimeout       time.Duration `yaml:"readTimeout"`
+	ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout"`
+	WriteTimeout      time.Duration `yaml:"writeTimeout"`
+	IdleTimeout       time.Duration `yaml:"idleTimeout"`
+	ShutdownTimeout   time.Duration `yaml:"shutdownTimeout"`
+	StaticDir         string        `yaml:"staticDir"`
+
+	// Serve the unversioned routes, which take the user from the path without authenticating them
+	LegacyRoutes bool `yaml:"legacyRoutes"`
+}
+
+// RedisConfig holds the settings of the Redis connection pool
+type RedisConfig struct {
+	Addr           string        `yaml:"addr"`
+	MaxIdle        int           `yaml:"maxIdle"`
+	MaxActive      int           `yaml:"maxActive"`
+	IdleTimeout    time.Duration `yaml:"idleTimeout"`
+	ConnectTimeout time.Duration `yaml:"connectTimeout"`
+}
+
+// WebSocketConfig holds the limits of WebSocket connections
+type WebSocketConfig struct {
+	ReadBufferSize  int `yaml:"readBufferSize"`
+	WriteBufferSize int `yaml:"writeBufferSize"`
+
+	// Time allowed to write a message to the peer.
+	WriteWait time.Duration `yaml:"writeWait"`
+
+	// Time allowed to read the next pong message from the peer.
+	// Pings are sent to the peer at 90% of this period.
+	PongWait time.Duration `yaml:"pongWait"`
+
+	// Send latency probes and stats messages to peer with this period.
+	StatsPeriod time.Duration `yaml:"statsPeriod"`
+
+	// Maximum message size allowed from peer.
+	MaxMessageSize int64 `yaml:"maxMessageSize"`
+
+	// Number of frames buffered for each client.
+	SendBufferSize int `yaml:"sendBufferSize"`
+}
+
+// HubConfig holds the settings of live game sessions
+type HubConfig struct {
+	TickInterval       time.Duration `yaml:"tickInterval"`
+	CheckpointInterval time.Duration `yaml:"checkpointInterval"`
+	Rehydrate          bool          `yaml:"rehydrate"`
+	MaxHubs            int           `yaml:"maxHubs"`
+	IdleTimeout        time.Duration `yaml:"idleTimeout"`
+}
+
+// RateLimitConfig holds the limits that protect the service from abuse
+type RateLimitConfig struct {
+	// Starting and loading live game sessions, per user and per IP address
+	HubCreation RateLimit `yaml:"hubCreation"`
+
+	// Saving live game sessions, per user and per IP address
+	Saves RateLimit `yaml:"saves"`
+
+	// Logging in, per user and per IP address
+	Logins RateLimit `yaml:"logins"`
+
+	// Input messages, per client
+	Inputs RateLimit `yaml:"inputs"`
+
+	// Live game sessions a user can have started at once, 0 for no limit
+	MaxHubsPerUser int `yaml:"maxHubsPerUser"`
+}
+
+// RateLimit is a token bucket refilled at PerSecond tokens a second, holding up to Burst tokens
+type RateLimit struct {
+	PerSecond float64 `yaml:"perSecond"`
+	Burst     int     `yaml:"burst"`
+}
+
+// CORSConfig holds the origins allowed to use the service from a browser
+type CORSConfig struct {
+	// Origins such as https://example.com, https://*.example.com, or * for any origin
+	// Same-origin requests are always allowed
+	AllowedOrigins []string `yaml:"allowedOrigins"`
+
+	// How long browsers may cache preflight responses
+	MaxAge time.Duration `yaml:"maxAge"`
+}
+
+// AuthConfig holds the settings of the bearer tokens used by the versioned API
+type AuthConfig struct {
+	// How long a token is accepted after it was issued
+	TokenTTL time.Duration `yaml:"tokenTTL"`
+
+	// Bearer tokens accepted by the admin API, which is disabled when there are none
+	AdminTokens []string `yaml:"adminTokens"`
+}
+
+// LoggingConfig holds the settings of the structured logger
+type LoggingConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// GameConfig holds the settings of one game, zero values fall back to the service-wide settings
+type GameConfig struct {
+	TickInterval time.Duration       `yaml:"tickInterval"`
+	Backpressure *BackpressurePolicy `yaml:"backpressure"`
+	Compression  string              `yaml:"compression"`
+
+	// Runs the game as a separate process instead of one built into the server
+	Process *GameProcessConfig `yaml:"process"`
+}
+
+// GameProcessConfig holds the settings of a game run as a separate process
+type GameProcessConfig struct {
+	// The executable and its arguments
+	Command []string `yaml:"command"`
+
+	// How the process is called, stdio or unix for a Unix socket
+	Transport string `yaml:"transport"`
+
+	// How long a call may take before the process is considered hung and restarted
+	CallTimeout time.Duration `yaml:"callTimeout"`
+
+	// How long a live game session waits for a tick before skipping it, the call carries on until callTimeout
+	TickTimeout time.Duration `yaml:"tickTimeout"`
+
+	// The longest wait between restarts of a process that keeps exiting
+	MaxRestartDelay time.Duration `yaml:"maxRestartDelay"`
+}
+
+// AppConfig is the configuration the service was started with
+var AppConfig = DefaultConfig()
+
+// envPrefix is prepended to the environment variables that override settings
+const envPrefix = "GAME_SHARING"
+
+// DefaultConfig returns the settings used when nothing else is configured
+func DefaultConfig() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:              ":8080",
+			WSAddr:            ":8082",
+			ReadHeaderTimeout: 10 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+			StaticDir:         "public",
+		},
+		Redis: RedisConfig{
+			Addr:           ":6379",
+			MaxIdle:        3,
+			ConnectTimeout: 5 * time.Second,
+		},
+		WebSocket: WebSocketConfig{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			WriteWait:       10 * time.Second,
+			PongWait:        60 * time.Second,
+			StatsPeriod:     5 * time.Second,
+			MaxMessageSize:  16,
+			SendBufferSize:  256,
+		},
+		Hubs: HubConfig{
+			TickInterval:       10 * time.Millisecond,
+			CheckpointInterval: 10 * time.Second,
+			Rehydrate:          true,
+			IdleTimeout:        15 * time.Minute,
+		},
+		RateLimits: RateLimitConfig{
+			HubCreation:    RateLimit{PerSecond: 0.2, Burst: 5},
+			Saves:          RateLimit{PerSecond: 1, Burst: 5},
+			Logins:         RateLimit{PerSecond: 1, Burst: 10},
+			Inputs:         RateLimit{PerSecond: 60, Burst: 120},
+			MaxHubsPerUser: 5,
+		},
+		CORS: CORSConfig{
+			MaxAge: 10 * time.Minute,
+		},
+		Auth: AuthConfig{
+			TokenTTL: 24 * time.Hour,
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		Games: make(map[GameID]GameConfig),
+	}
+}
+
+// bindFlags registers the command line flags that override settings
+func bindFlags(flags *flag.FlagSet, config *Config) {
+	flags.StringVar(&config.Server.Addr, "addr", config.Server.Addr, "HTTP service address")
+	flags.StringVar(&config.Server.WSAddr, "wsAddr", config.Server.WSAddr, "WebSocket service address")
+	flags.BoolVar(&config.Server.Unified, "unified", config.Server.Unified, "Serve the WebSocket routes on the HTTP service address instead of wsAddr")
+	flags.StringVar(&config.Server.TLSCert, "tlsCert", config.Server.TLSCert, "TLS certificate file, enables HTTPS and WSS along with tlsKey")
+	flags.StringVar(&config.Server.TLSKey, "tlsKey", config.Server.TLSKey, "TLS private key file")
+	flags.DurationVar(&config.Server.ReadTimeout, "readTimeout", config.Server.ReadTimeout, "Maximum duration for reading an entire request (0 for no limit)")
+	flags.DurationVar(&config.Server.ReadHeaderTimeout, "readHeaderTimeout", config.Server.ReadHeaderTimeout, "Maximum duration for reading request headers")
+	flags.DurationVar(&config.Server.WriteTimeout, "writeTimeout", config.Server.WriteTimeout, "Maximum duration before timing out writes of a response (0 for no limit)")
+	flags.DurationVar(&config.Server.IdleTimeout, "idleTimeout", config.Server.IdleTimeout, "Maximum time to wait for the next request on a keep-alive connection")
+	flags.DurationVar(&config.Server.ShutdownTimeout, "shutdownTimeout", config.Server.ShutdownTimeout, "Time allowed for requests and connections to finish when shutting down")
+	flags.BoolVar(&config.Server.LegacyRoutes, "legacyRoutes", config.Server.LegacyRoutes, "Serve the unversioned routes, which do not authenticate users")
+	flags.StringVar(&config.Redis.Addr, "redisAddr", config.Redis.Addr, "Redis service address")
+	flags.DurationVar(&config.Hubs.CheckpointInterval, "checkpointInterval", config.Hubs.CheckpointInterval, "Time between checkpoints of each live game session (0 to only checkpoint when players leave)")
+	flags.BoolVar(&config.Hubs.Rehydrate, "rehydrate", config.Hubs.Rehydrate, "Restore checkpointed live game sessions on startup")
+	flags.IntVar(&config.Hubs.MaxHubs, "maxHubs", config.Hubs.MaxHubs, "Live game sessions at which the service stops being ready (0 for no limit)")
+	flags.StringVar(&config.Logging.Level, "logLevel", config.Logging.Level, "Log level (debug, info, warn or error)")
+	flags.StringVar(&config.Logging.Format, "logFormat", config.Logging.Format, "Log format (text or json)")
+}
+
+// LoadConfig builds the configuration from the file, environment and command line arguments
+// It also returns the arguments after the flags, which name a maintenance command
+func LoadConfig(args []string) (*Config, []string, error) {
+	// Parse the flags once to find the config file and which flags were given
+	parsed := DefaultConfig()
+	flags := flag.NewFlagSet("game-sharing", flag.ContinueOnError)
+	configPath := flags.String("config", os.Getenv(envPrefix+"_CONFIG"), "YAML or JSON configuration file")
+	bindFlags(flags, parsed)
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	config := DefaultConfig()
+
+	if *configPath != "" {
+		data, err := os.ReadFile(*configPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("config: reading %s: %w", *configPath, err)
+		}
+		// YAML is a superset of JSON, so this reads both
+		decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(config); err != nil {
+			return nil, nil, fmt.Errorf("config: parsing %s: %w", *configPath, err)
+		}
+	}
+
+	if err := applyEnv(reflect.ValueOf(config).Elem(), envPrefix); err != nil {
+		return nil, nil, err
+	}
+
+	// Flags given on the command line take precedence over everything else
+	overrides := flag.NewFlagSet("overrides", flag.ContinueOnError)
+	bindFlags(overrides, config)
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name != "config" {
+			overrides.Set(f.Name, f.Value.String())
+		}
+	})
+
+	if err := config.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return config, flags.Args(), nil
+}
+
+// applyEnv overrides settings from environment variables named after their path,
+// e.g. redis.maxIdle is read from GAME_SHARING_REDIS_MAX_IDLE
+// Maps such as games have no fixed paths, so they can only be set in the config file
+func applyEnv(value reflect.Value, prefix string) error {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		name := prefix + "_" + envName(value.Type().Field(i).Tag.Get("yaml"))
+
+		if field.Kind() == reflect.Struct {
+			if err := applyEnv(field, name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		var err error
+		switch field.Interface().(type) {
+		case string:
+			field.SetString(raw)
+		case bool:
+			var parsed bool
+			parsed, err = strconv.ParseBool(raw)
+			field.SetBool(parsed)
+		case time.Duration:
+			var parsed time.Duration
+			parsed, err = time.ParseDuration(raw)
+			field.SetInt(int64(parsed))
+		case []string:
+			// Lists are comma separated
+			list := []string{}
+			for _, item := range strings.Split(raw, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+			field.Set(reflect.ValueOf(list))
+		case float64:
+			var parsed float64
+			parsed, err = strconv.ParseFloat(raw, 64)
+			field.SetFloat(parsed)
+		case int, int64:
+			var parsed int64
+			parsed, err = strconv.ParseInt(raw, 10, 64)
+			field.SetInt(parsed)
+		default:
+			if field.Kind() == reflect.Map {
+				return fmt.Errorf("config: %s cannot be set from the environment, set %s in the config file instead", name, value.Type().Field(i).Tag.Get("yaml"))
+			}
+			return fmt.Errorf("config: %s cannot be set from the environment", name)
+		}
+		if err != nil {
+			return fmt.Errorf("config: invalid value %q for %s: %w", raw, name, err)
+		}
+	}
+	return nil
+}
+
+// envName converts a camelCase setting name to SCREAMING_SNAKE_CASE
+func envName(name string) string {
+	var builder strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			builder.WriteRune('_')
+		}
+		builder.WriteRune(unicode.ToUpper(r))
+	}
+	return builder.String()
+}
+
+// Validate checks the settings, reporting every problem found
+func (config *Config) Validate() error {
+	var problems []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Errorf("config: "+format, args...))
+		}
+	}
+
+	server := config.Server
+	check(server.Addr != "", "server.addr must be set")
+	check(server.Unified || server.WSAddr != "", "server.wsAddr must be set unless server.unified is true")
+	check(server.Unified || server.Addr != server.WSAddr, "server.addr and server.wsAddr must differ unless server.unified is true")
+	check((server.TLSCert == "") == (server.TLSKey == ""), "server.tlsCert and server.tlsKey must be set together")
+	check(server.ReadTimeout >= 0, "server.readTimeout must not be negative")
+	check(server.ReadHeaderTimeout >= 0, "server.readHeaderTimeout must not be negative")
+	check(server.WriteTimeout >= 0, "server.writeTimeout must not be negative")
+	check(server.IdleTimeout >= 0, "server.idleTimeout must not be negative")
+	check(server.ShutdownTimeout > 0, "server.shutdownTimeout must be positive")
+
+	redisConfig := config.Redis
+	check(redisConfig.Addr != "", "redis.addr must be set")
+	check(redisConfig.MaxIdle >= 0, "redis.maxIdle must not be negative")
+	check(redisConfig.MaxActive >= 0, "redis.maxActive must not be negative (0 for no limit)")
+	check(redisConfig.MaxActive == 0 || redisConfig.MaxIdle <= redisConfig.MaxActive, "redis.maxIdle must not exceed redis.maxActive")
+	check(redisConfig.IdleTimeout >= 0, "redis.idleTimeout must not be negative")
+	check(redisConfig.ConnectTimeout >= 0, "redis.connectTimeout must not be negative")
+
+	webSocket := config.WebSocket
+	check(webSocket.ReadBufferSize >= 0, "websocket.readBufferSize must not be negative")
+	check(webSocket.WriteBufferSize >= 0, "websocket.writeBufferSize must not be negative")
+	check(webSocket.WriteWait > 0, "websocket.writeWait must be positive")
+	check(webSocket.PongWait > 0, "websocket.pongWait must be positive")
+	check(webSocket.StatsPeriod > 0, "websocket.statsPeriod must be positive")
+	check(webSocket.MaxMessageSize > 0, "websocket.maxMessageSize must be positive")
+	check(webSocket.SendBufferSize > 0, "websocket.sendBufferSize must be positive")
+
+	hubs := config.Hubs
+	check(hubs.TickInterval > 0, "hubs.tickInterval must be positive")
+	check(hubs.CheckpointInterval >= 0, "hubs.checkpointInterval must not be negative (0 to only checkpoint when players leave)")
+	check(hubs.MaxHubs >= 0, "hubs.maxHubs must not be negative (0 for no limit)")
+	check(hubs.IdleTimeout >= 0, "hubs.idleTimeout must not be negative (0 to keep idle sessions)")
+
+	limits := config.RateLimits
+	for _, named := range []struct {
+		name  string
+		limit RateLimit
+	}{
+		{"hubCreation", limits.HubCreation},
+		{"saves", limits.Saves},
+		{"logins", limits.Logins},
+		{"inputs", limits.Inputs},
+	} {
+		name, limit := named.name, named.limit
+		check(limit.PerSecond >= 0, "rateLimits.%s.perSecond must not be negative (0 for no limit)", name)
+		check(limit.PerSecond == 0 || limit.Burst >= 1, "rateLimits.%s.burst must be at least 1", name)
+	}
+	check(limits.MaxHubsPerUser >= 0, "rateLimits.maxHubsPerUser must not be negative (0 for no limit)")
+
+	for _, origin := range config.CORS.AllowedOrigins {
+		check(validateOrigin(origin), "cors.allowedOrigins entry %q must be *, an origin such as https://example.com, or a wildcard such as https://*.example.com", origin)
+	}
+	check(config.CORS.MaxAge >= 0, "cors.maxAge must not be negative")
+	check(config.Auth.TokenTTL > 0, "auth.tokenTTL must be positive")
+	for _, token := range config.Auth.AdminTokens {
+		check(len(token) >= 16, "auth.adminTokens entries must be at least 16 characters")
+	}
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(config.Logging.Level)) == nil, "logging.level %q must be debug, info, warn or error", config.Logging.Level)
+	format := strings.ToLower(config.Logging.Format)
+	check(format == "text" || format == "json", "logging.format %q must be text or json", config.Logging.Format)
+
+	for gameID, game := range config.Games {
+		check(game.TickInterval >= 0, "games.%s.tickInterval must not be negative", gameID)
+		check(game.Compression == "" || game.Compression == compressionNone || game.Compression == compressionGzip, "games.%s.compression must be none or gzip", gameID)
+		if policy := game.Backpressure; policy != nil {
+			check(policy.CongestionThreshold > 0 && policy.CongestionThreshold <= 1, "games.%s.backpressure.congestionThreshold must be in (0, 1]", gameID)
+			check(policy.RecoveryThreshold >= 0 && policy.RecoveryThreshold < policy.CongestionThreshold, "games.%s.backpressure.recoveryThreshold must be in [0, congestionThreshold)", gameID)
+			check(policy.MaxFrameInterval >= 1, "games.%s.backpressure.maxFrameInterval must be at least 1", gameID)
+			check(policy.RecoveryFrames >= 1, "games.%s.backpressure.recoveryFrames must be at least 1", gameID)
+			check(policy.MaxOverloadDuration > 0, "games.%s.backpressure.maxOverloadDuration must be positive", gameID)
+		}
+		if process := game.Process; process != nil {
+			check(len(process.Command) > 0, "games.%s.process.command must be set", gameID)
+			check(process.Transport == "" || process.Transport == transportStdio || process.Transport == transportUnix, "games.%s.process.transport must be stdio or unix", gameID)
+			check(process.CallTimeout >= 0, "games.%s.process.callTimeout must not be negative", gameID)
+			check(process.TickTimeout >= 0, "games.%s.process.tickTimeout must not be negative", gameID)
+			check(process.MaxRestartDelay >= 0, "games.%s.process.maxRestartDelay must not be negative", gameID)
+		}
+	}
+
+	return errors.Join(problems...)
+}
+
+// GameSettings returns the settings of a game, falling back to the service-wide settings
+func (config *Config) GameSettings(gameID GameID) GameConfig {
+	settings := config.Games[gameID]
+	if settings.TickInterval == 0 {
+		settings.TickInterval = config.Hubs.TickInterval
+	}
+	if settings.Backpressure == nil {
+		policy := DefaultBackpressurePolicy
+		settings.Backpressure = &policy
+	}
+	if settings.Process != nil {
+		process := *settings.Process
+		if process.Transport == "" {
+			process.Transport = transportStdio
+		}
+		if process.CallTimeout == 0 {
+			process.CallTimeout = time.Second
+		}
+		if process.TickTimeout == 0 {
+			process.TickTimeout = 100 * time.Millisecond
+		}
+		if process.MaxRestartDelay == 0 {
+			process.MaxRestartDelay = 30 * time.Second
+		}
+		settings.Process = &process
+	}
+	return settings
+}
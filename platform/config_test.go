@@ -0,0 +1,221 @@
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFile writes the config file for the test and returns its path
+func writeConfigFile(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*Config)
+		want   string
+	}{
+		{"defaults", func(*Config) {}, ""},
+		{"no address", func(c *Config) { c.Server.Addr = "" }, "server.addr must be set"},
+		{"same addresses", func(c *Config) { c.Server.WSAddr = c.Server.Addr }, "server.addr and server.wsAddr must differ"},
+		{"same addresses unified", func(c *Config) { c.Server.WSAddr = c.Server.Addr; c.Server.Unified = true }, ""},
+		{"certificate without key", func(c *Config) { c.Server.TLSCert = "cert.pem" }, "server.tlsCert and server.tlsKey"},
+		{"idle connections above the limit", func(c *Config) { c.Redis.MaxIdle, c.Redis.MaxActive = 5, 2 }, "redis.maxIdle must not exceed"},
+		{"no send buffer", func(c *Config) { c.WebSocket.SendBufferSize = 0 }, "websocket.sendBufferSize must be positive"},
+		{"negative hub limit", func(c *Config) { c.Hubs.MaxHubs = -1 }, "hubs.maxHubs must not be negative"},
+		{"rate without burst", func(c *Config) { c.RateLimits.Saves = RateLimit{PerSecond: 1} }, "rateLimits.saves.burst"},
+		{"bad origin", func(c *Config) { c.CORS.AllowedOrigins = []string{"example.com"} }, "cors.allowedOrigins entry"},
+		{"short admin token", func(c *Config) { c.Auth.AdminTokens = []string{"short"} }, "auth.adminTokens"},
+		{"unknown log level", func(c *Config) { c.Logging.Level = "loud" }, "logging.level"},
+		{"unknown compression", func(c *Config) { c.Games["0"] = GameConfig{Compression: "zip"} }, "games.0.compression"},
+		{"backpressure thresholds", func(c *Config) {
+			policy := DefaultBackpressurePolicy
+			policy.RecoveryThreshold = policy.CongestionThreshold
+			c.Games["0"] = GameConfig{Backpressure: &policy}
+		}, "games.0.backpressure.recoveryThreshold"},
+		{"process without command", func(c *Config) { c.Games["1"] = GameConfig{Process: &GameProcessConfig{}} }, "games.1.process.command"},
+		{"negative tick timeout", func(c *Config) {
+			c.Games["1"] = GameConfig{Process: &GameProcessConfig{Command: []string{"game"}, TickTimeout: -time.Second}}
+		}, "games.1.process.tickTimeout"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := DefaultConfig()
+			test.change(config)
+			err := config.Validate()
+			if test.want == "" && err != nil {
+				t.Errorf("got error %v, want none", err)
+			} else if test.want != "" && (err == nil || !strings.Contains(err.Error(), test.want)) {
+				t.Errorf("got error %v, want one mentioning %q", err, test.want)
+			}
+		})
+	}
+
+	// Every problem is reported at once
+	config := DefaultConfig()
+	config.Server.Addr, config.Logging.Format = "", "xml"
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "server.addr") || !strings.Contains(err.Error(), "logging.format") {
+		t.Errorf("got error %v, want both problems", err)
+	}
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	path := writeConfigFile(t, `
+server:
+  addr: ":9000"
+  wsAddr: ":9001"
+redis:
+  addr: "file:6379"
+  maxIdle: 7
+`)
+	t.Setenv("GAME_SHARING_REDIS_ADDR", "env:6379")
+	t.Setenv("GAME_SHARING_SERVER_WS_ADDR", ":9101")
+
+	config, command, err := LoadConfig([]string{"-config", path, "-wsAddr", ":9201", "migrate-states", "-dryRun"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		setting string
+		got     interface{}
+		want    interface{}
+	}{
+		{"from the file", config.Server.Addr, ":9000"},
+		{"from the file, not in the environment", config.Redis.MaxIdle, 7},
+		{"from the environment over the file", config.Redis.Addr, "env:6379"},
+		{"from a flag over the environment", config.Server.WSAddr, ":9201"},
+		{"default", config.Hubs.TickInterval, 10 * time.Millisecond},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s: got %v, want %v", test.setting, test.got, test.want)
+		}
+	}
+	if !reflect.DeepEqual(command, []string{"migrate-states", "-dryRun"}) {
+		t.Errorf("got command %v, want migrate-states -dryRun", command)
+	}
+}
+
+func TestLoadConfigEnv(t *testing.T) {
+	tests := []struct {
+		name  string
+		env   string
+		value string
+		get   func(*Config) interface{}
+		want  interface{}
+		err   string
+	}{
+		{"string", "GAME_SHARING_LOGGING_FORMAT", "json", func(c *Config) interface{} { return c.Logging.Format }, "json", ""},
+		{"bool", "GAME_SHARING_SERVER_LEGACY_ROUTES", "true", func(c *Config) interface{} { return c.Server.LegacyRoutes }, true, ""},
+		{"duration", "GAME_SHARING_AUTH_TOKEN_TTL", "90m", func(c *Config) interface{} { return c.Auth.TokenTTL }, 90 * time.Minute, ""},
+		{"list", "GAME_SHARING_CORS_ALLOWED_ORIGINS", "https://a.example.com, ,https://*.b.example.com", func(c *Config) interface{} { return c.CORS.AllowedOrigins }, []string{"https://a.example.com", "https://*.b.example.com"}, ""},
+		{"float", "GAME_SHARING_RATE_LIMITS_SAVES_PER_SECOND", "2.5", func(c *Config) interface{} { return c.RateLimits.Saves.PerSecond }, 2.5, ""},
+		{"int", "GAME_SHARING_RATE_LIMITS_MAX_HUBS_PER_USER", "3", func(c *Config) interface{} { return c.RateLimits.MaxHubsPerUser }, 3, ""},
+		{"int64", "GAME_SHARING_WEBSOCKET_MAX_MESSAGE_SIZE", "64", func(c *Config) interface{} { return c.WebSocket.MaxMessageSize }, int64(64), ""},
+		{"invalid bool", "GAME_SHARING_HUBS_REHYDRATE", "maybe", nil, nil, "invalid value"},
+		{"invalid duration", "GAME_SHARING_HUBS_IDLE_TIMEOUT", "soon", nil, nil, "invalid value"},
+		{"invalid setting", "GAME_SHARING_LOGGING_LEVEL", "loud", nil, nil, "logging.level"},
+		{"map", "GAME_SHARING_GAMES", `{"0":{}}`, nil, nil, "set games in the config file instead"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(test.env, test.value)
+			config, _, err := LoadConfig(nil)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want one mentioning %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := test.get(config); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigGames(t *testing.T) {
+	path := writeConfigFile(t, `
+games:
+  "0":
+    compression: gzip
+    backpressure:
+      coalesceFrames: false
+      maxOverloadDuration: 2s
+  "1":
+    process:
+      command: ["./newgame"]
+`)
+	config, _, err := LoadConfig([]string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Omitted backpressure settings are the defaults
+	want := DefaultBackpressurePolicy
+	want.CoalesceFrames = false
+	want.MaxOverloadDuration = 2 * time.Second
+	if got := *config.GameSettings("0").Backpressure; got != want {
+		t.Errorf("got policy %+v, want %+v", got, want)
+	}
+	if got := *config.GameSettings("1").Backpressure; got != DefaultBackpressurePolicy {
+		t.Errorf("got policy %+v, want the default", got)
+	}
+
+	process := config.GameSettings("1").Process
+	if process.Transport != transportStdio || process.CallTimeout != time.Second || process.TickTimeout != 100*time.Millisecond {
+		t.Errorf("got process settings %+v, want the defaults", process)
+	}
+	if settings := config.GameSettings("2"); settings.TickInterval != config.Hubs.TickInterval || settings.Process != nil {
+		t.Errorf("got settings %+v for an unconfigured game, want the service-wide ones", settings)
+	}
+}
+
+func TestLoadConfigRejectsFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     string
+	}{
+		{"unknown setting", "server:\n  address: \":80\"\n", "address"},
+		{"unknown backpressure setting", "games:\n  \"0\":\n    backpressure:\n      coalesce: true\n", `unknown backpressure setting "coalesce"`},
+		{"invalid backpressure setting", "games:\n  \"0\":\n    backpressure:\n      recoveryThreshold: 0.9\n", "games.0.backpressure.recoveryThreshold"},
+		{"wrong type", "hubs:\n  maxHubs: many\n", "many"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := LoadConfig([]string{"-config", writeConfigFile(t, test.contents)})
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got error %v, want one mentioning %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"addr":              "ADDR",
+		"maxIdle":           "MAX_IDLE",
+		"wsAddr":            "WS_ADDR",
+		"tokenTTL":          "TOKEN_TTL",
+		"tlsCert":           "TLS_CERT",
+		"readHeaderTimeout": "READ_HEADER_TIMEOUT",
+	}
+	for name, want := range tests {
+		if got := envName(name); got != want {
+			t.Errorf("envName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package platform
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Methods and headers that cross-origin requests to the REST API may use
+const (
+	corsAllowedMethods = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+	corsAllowedHeaders = "Authorization, Content-Type, X-Request-ID"
+	corsExposedHeaders = "Location, Retry-After, WWW-Authenticate, X-Request-ID"
+)
+
+// validateOrigin checks that an allow-list entry is "*" or an origin such as https://*.example.com
+func validateOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+	scheme, host, found := strings.Cut(origin, "://")
+	if !found || (scheme != "http" && scheme != "https") {
+		return false
+	}
+	host = strings.TrimPrefix(host, "*.")
+	return host != "" && !strings.ContainsAny(host, "*/")
+}
+
+// originAllowed reports whether a request from the origin may use the service on host
+// Requests without an origin (i.e. not from a browser) and same-origin requests are always allowed
+func (config *CORSConfig) originAllowed(origin string, host string) bool {
+	if origin == "" {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(parsed.Host, host) {
+		return true
+	}
+
+	for _, allowed := range config.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+
+		// Wildcard subdomains, e.g. https://*.example.com
+		scheme, pattern, found := strings.Cut(allowed, "://*.")
+		if found && strings.EqualFold(parsed.Scheme, scheme) &&
+			strings.HasSuffix(strings.ToLower(parsed.Host), "."+strings.ToLower(pattern)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkWebSocketOrigin enforces the origin allow-list on WebSocket upgrades
+func checkWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if !AppConfig.CORS.originAllowed(origin, r.Host) {
+		requestLogger(r).Warn("websocket origin rejected", "origin", origin)
+		return false
+	}
+	return true
+}
+
+// CORSMiddleware answers preflight requests and adds CORS headers for allowed origins
+func CORSMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed := AppConfig.CORS.originAllowed(origin, r.Host)
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		w.Header().Add("Vary", "Origin")
+
+		if preflight {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			if !allowed {
+				writeError(w, http.StatusForbidden, CodeOriginNotAllowed, "Origin not allowed.")
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", corsAllowedMethods)
+			w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+			if maxAge := AppConfig.CORS.MaxAge; maxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(maxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		// Browsers block the response from disallowed origins without these headers
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,140 @@
+package platform
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestValidateOrigin(t *testing.T) {
+	tests := map[string]bool{
+		"*":                         true,
+		"https://example.com":       true,
+		"http://localhost:3000":     true,
+		"https://*.example.com":     true,
+		"example.com":               false,
+		"ftp://example.com":         false,
+		"https://":                  false,
+		"https://*.":                false,
+		"https://*.*.example.com":   false,
+		"https://example.com/path":  false,
+		"https://www.*.example.com": false,
+	}
+	for origin, want := range tests {
+		if got := validateOrigin(origin); got != want {
+			t.Errorf("validateOrigin(%q) = %v, want %v", origin, got, want)
+		}
+	}
+}
+
+func TestOriginAllowed(t *testing.T) {
+	config := &CORSConfig{AllowedOrigins: []string{"https://app.example.com", "https://*.games.example.com", "http://localhost:3000"}}
+	tests := []struct {
+		name   string
+		origin string
+		host   string
+		want   bool
+	}{
+		{"no origin", "", "api.example.com", true},
+		{"same origin", "https://api.example.com", "api.example.com", true},
+		{"same origin with a port", "http://localhost:8080", "localhost:8080", true},
+		{"listed origin", "https://app.example.com", "api.example.com", true},
+		{"listed origin in another case", "https://APP.example.com", "api.example.com", true},
+		{"listed origin with another scheme", "http://app.example.com", "api.example.com", false},
+		{"listed origin with a port", "http://localhost:3000", "api.example.com", true},
+		{"listed origin with another port", "http://localhost:3001", "api.example.com", false},
+		{"wildcard subdomain", "https://chess.games.example.com", "api.example.com", true},
+		{"wildcard nested subdomain", "https://a.chess.games.example.com", "api.example.com", true},
+		{"wildcard domain itself", "https://games.example.com", "api.example.com", false},
+		{"wildcard with another scheme", "http://chess.games.example.com", "api.example.com", false},
+		{"wildcard as a suffix of another domain", "https://evilgames.example.com", "api.example.com", false},
+		{"unlisted origin", "https://evil.com", "api.example.com", false},
+		{"listed origin as a prefix", "https://app.example.com.evil.com", "api.example.com", false},
+		{"malformed origin", "https://%zz", "api.example.com", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := config.originAllowed(test.origin, test.host); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	anyOrigin := &CORSConfig{AllowedOrigins: []string{"*"}}
+	if !anyOrigin.originAllowed("https://evil.com", "api.example.com") {
+		t.Error("* did not allow every origin")
+	}
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	AppConfig = DefaultConfig()
+	AppConfig.CORS = CORSConfig{AllowedOrigins: []string{"https://app.example.com"}, MaxAge: 10 * time.Minute}
+	handler := CORSMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	tests := []struct {
+		name        string
+		method      string
+		origin      string
+		preflight   bool
+		status      int
+		allowOrigin string
+		maxAge      string
+	}{
+		{"no origin", "GET", "", false, http.StatusTeapot, "", ""},
+		{"allowed request", "GET", "https://app.example.com", false, http.StatusTeapot, "https://app.example.com", ""},
+		{"disallowed request", "GET", "https://evil.com", false, http.StatusTeapot, "", ""},
+		{"allowed preflight", "OPTIONS", "https://app.example.com", true, http.StatusNoContent, "https://app.example.com", "600"},
+		{"disallowed preflight", "OPTIONS", "https://evil.com", true, http.StatusForbidden, "", ""},
+		{"options without a preflight", "OPTIONS", "https://app.example.com", false, http.StatusTeapot, "https://app.example.com", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, "http://api.example.com/v1/games", nil)
+			if test.origin != "" {
+				request.Header.Set("Origin", test.origin)
+			}
+			if test.preflight {
+				request.Header.Set("Access-Control-Request-Method", "POST")
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != test.status {
+				t.Errorf("got status %d, want %d", recorder.Code, test.status)
+			}
+			if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != test.allowOrigin {
+				t.Errorf("got allowed origin %q, want %q", got, test.allowOrigin)
+			}
+			if got := recorder.Header().Get("Access-Control-Max-Age"); got != test.maxAge {
+				t.Errorf("got max age %q, want %q", got, test.maxAge)
+			}
+			if test.origin != "" && recorder.Header().Get("Vary") != "Origin" {
+				t.Errorf("responses to cross-origin requests must vary by origin, got %q", recorder.Header().Values("Vary"))
+			}
+		})
+	}
+}
+
+func TestCheckWebSocketOrigin(t *testing.T) {
+	AppConfig = DefaultConfig()
+	AppConfig.CORS.AllowedOrigins = []string{"https://app.example.com"}
+
+	tests := map[string]bool{
+		"":                        true,
+		"https://ws.example.com":  true,
+		"https://app.example.com": true,
+		"https://evil.com":        false,
+	}
+	for origin, want := range tests {
+		request := httptest.NewRequest("GET", "http://ws.example.com/play", nil)
+		if origin != "" {
+			request.Header.Set("Origin", origin)
+		}
+		if got := checkWebSocketOrigin(request); got != want {
+			t.Errorf("origin %q: got %v, want %v", origin, got, want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package platform
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Errors returned by the game servers, checked with errors.Is
+var (
+	ErrStateNotFound     = errors.New("state ID not in database")
+	ErrNotLiveSession    = errors.New("state ID is not a live game session")
+	ErrStateAlreadySaved = errors.New("new state ID already exists")
+	ErrStateEncoding     = errors.New("state could not be encoded")
+	ErrStateDecoding     = errors.New("state did not decode correctly")
+	ErrDatabase          = errors.New("database error")
+)
+
+// Error codes sent to API clients, these must not change once published
+const (
+	CodeGameNotFound       = "game_not_found"
+	CodeGameExists         = "game_exists"
+	CodeGameInUse          = "game_in_use"
+	CodeNoGameServer       = "no_game_server"
+	CodeThumbnailNotFound  = "thumbnail_not_found"
+	CodeUserNotFound       = "user_not_found"
+	CodeInvalidStateID     = "invalid_state_id"
+	CodeInvalidRequest     = "invalid_request"
+	CodeStateNotFound      = "state_not_found"
+	CodePreviewNotFound    = "preview_not_found"
+	CodeNotLiveSession     = "not_live_session"
+	CodeClientNotFound     = "client_not_found"
+	CodeStateConflict      = "state_conflict"
+	CodeStateCorrupt       = "state_corrupt"
+	CodeUnauthorized       = "unauthorized"
+	CodeInvalidCredentials = "invalid_credentials"
+	CodeNotSessionPlayer   = "not_session_player"
+	CodeOriginNotAllowed   = "origin_not_allowed"
+	CodeRateLimited        = "rate_limited"
+	CodeTooManySessions    = "too_many_live_sessions"
+	CodeDatabaseError      = "database_error"
+	CodeNotFound           = "not_found"
+	CodeMethodNotAllowed   = "method_not_allowed"
+	CodeInternalError      = "internal_error"
+)
+
+// APIError is the body of every error response
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NotFound replies to requests for paths that no route matches
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, CodeNotFound, "No endpoint matches the path.")
+}
+
+// MethodNotAllowed replies to requests for a path with a method none of its routes accept
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "The endpoint does not accept the method.")
+}
+
+// writeError replies to the request with a JSON error body
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Error APIError `json:"error"`
+	}{APIError{Code: code, Message: message}})
+}
+
+// writeStateError replies with the status and code matching an error from a game server
+func writeStateError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrStateNotFound):
+		writeError(w, http.StatusNotFound, CodeStateNotFound, "State ID not in database.")
+	case errors.Is(err, ErrNotLiveSession):
+		writeError(w, http.StatusNotFound, CodeNotLiveSession, "State ID is not a valid live game session.")
+	case errors.Is(err, ErrStateAlreadySaved):
+		writeError(w, http.StatusConflict, CodeStateConflict, "New state ID already exists.")
+	case errors.Is(err, ErrStateDecoding):
+		writeError(w, http.StatusInternalServerError, CodeStateCorrupt, "State did not decode correctly.")
+	case errors.Is(err, ErrDatabase):
+		writeError(w, http.StatusInternalServerError, CodeDatabaseError, "Database error encountered.")
+	default:
+		writeError(w, http.StatusInternalServerError, CodeInternalError, "Internal server error.")
+	}
+}
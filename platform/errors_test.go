@@ -0,0 +1,68 @@
+package platform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUnmatchedRequests(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		status int
+		code   string
+	}{
+		{"GET", "/nope", http.StatusNotFound, CodeNotFound},
+		{"GET", "/v1/nope", http.StatusNotFound, CodeNotFound},
+		{"GET", "/v1/games/0/nope/deeper", http.StatusNotFound, CodeNotFound},
+		{"DELETE", "/v1/games", http.StatusMethodNotAllowed, CodeMethodNotAllowed},
+		{"PUT", "/games", http.StatusMethodNotAllowed, CodeMethodNotAllowed},
+		{"GET", "/v1/users", http.StatusMethodNotAllowed, CodeMethodNotAllowed},
+	}
+	api := newTestAPI(t)
+	for _, test := range tests {
+		t.Run(test.method+" "+test.path, func(t *testing.T) {
+			status, body := api.do(test.method, test.path, "", "")
+			if status != test.status || errorCode(body) != test.code {
+				t.Errorf("got status %d and code %q, want %d and %q", status, errorCode(body), test.status, test.code)
+			}
+		})
+	}
+}
+
+func TestWriteStateError(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{ErrStateNotFound, http.StatusNotFound, CodeStateNotFound},
+		{ErrNotLiveSession, http.StatusNotFound, CodeNotLiveSession},
+		{ErrStateAlreadySaved, http.StatusConflict, CodeStateConflict},
+		{ErrStateDecoding, http.StatusInternalServerError, CodeStateCorrupt},
+		{fmt.Errorf("%w: schema version 0 is not valid", ErrStateDecoding), http.StatusInternalServerError, CodeStateCorrupt},
+		{fmt.Errorf("%w: connection refused", ErrDatabase), http.StatusInternalServerError, CodeDatabaseError},
+		{errors.New("unexpected"), http.StatusInternalServerError, CodeInternalError},
+	}
+	for _, test := range tests {
+		t.Run(test.err.Error(), func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			writeStateError(recorder, test.err)
+
+			body := map[string]interface{}{}
+			if err := json.NewDecoder(recorder.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if recorder.Code != test.status || errorCode(body) != test.code {
+				t.Errorf("got status %d and code %q, want %d and %q", recorder.Code, errorCode(body), test.status, test.code)
+			}
+			if contentType := recorder.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("got content type %q, want application/json", contentType)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package platform
+
+import (
+	"sync"
+	"time"
+
+	"github.com/game-sharing/service/sdk"
+)
+
+// GameServer is an interface for the main actions of the game
+// ServerLogic implements it for every game written with the SDK
+type GameServer interface {
+	// Run the game logic given certain inputs
+	// The GameState will be updated along with new DisplayData
+	ProcessState(sdk.GameState, sdk.InputData)
+
+	// Save and load the game state, returning errors matching the Err variables
+	// States are saved as encoded by the game loop of their live game session
+	SaveAsState([]byte) (StateID, error)
+	LoadState(StateID) (*SessionState, error)
+	NewState() *SessionState
+	NewStateID() StateID
+
+	// Restore a live game session from its checkpoint
+	RestoreCheckpoint(StateID, []byte) (*SessionState, error)
+
+	// The game the server runs
+	GetGameID() GameID
+
+	// How the game's hubs treat clients that cannot keep up
+	GetBackpressurePolicy() BackpressurePolicy
+
+	// The codec the game's states are saved with
+	GetStateCodec() sdk.StateCodec
+
+	// The game itself, for the optional interfaces of the SDK it implements
+	GetGame() sdk.Game
+}
+
+// SessionState wraps a game's state with the metadata of the session playing or saving it
+type SessionState struct {
+	id        StateID
+	serverID  GameServerID
+	savedDate time.Time
+
+	// The game's own state
+	State sdk.GameState
+}
+
+// GetID returns the StateID used to access the state
+func (session *SessionState) GetID() StateID {
+	return session.id
+}
+
+// GetServerID returns the GameServerID, essentially the game the state is for
+func (session *SessionState) GetServerID() GameServerID {
+	return session.serverID
+}
+
+// GetDisplayData returns the current display data of the game's state
+func (session *SessionState) GetDisplayData() sdk.DisplayData {
+	return session.State.GetDisplayData()
+}
+
+// GetSavedDate returns the time the game state was saved
+func (session *SessionState) GetSavedDate() time.Time {
+	return session.savedDate
+}
+
+// IsLiveSession returns true if the state has not been saved yet (i.e. it is a live session being played)
+func (session *SessionState) IsLiveSession() bool {
+	return session.savedDate.IsZero()
+}
+
+// StateID is a generated unique id for each GameState
+type StateID = int
+
+// SafeStateID is a StateID safe to use concurrently, prevents race condition
+type SafeStateID struct {
+	id  StateID
+	mux sync.Mutex
+}
+
+// GameServerID is a constant that identifies which game is being played
+type GameServerID = int
+
+// GetAndIncrementSafeStateID returns the current value of the SafeStateID and increments it after
+func (safeStateID *SafeStateID) GetAndIncrementSafeStateID() StateID {
+	// Lock so only one goroutine at a time can access the StateID
+	safeStateID.mux.Lock()
+	newStateID := safeStateID.id
+	safeStateID.id++
+	safeStateID.mux.Unlock()
+	return newStateID
+}
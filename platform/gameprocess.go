@@ -0,0 +1,321 @@
+package platform
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/game-sharing/service/sdk"
+)
+
+// Transports game processes are called over
+const (
+	transportStdio = "stdio"
+	transportUnix  = "unix"
+)
+
+// Timings of the supervision of game processes
+const (
+	gameProcessStartTimeout = 10 * time.Second
+	gameProcessStopTimeout  = 5 * time.Second
+	minRestartDelay         = 100 * time.Millisecond
+
+	// A process that ran this long before exiting is restarted without waiting longer than the first time
+	stableProcessDuration = time.Minute
+)
+
+// Errors returned by calls to game processes
+var (
+	errGameProcessDown    = errors.New("game process is not running")
+	errGameProcessTimeout = errors.New("game process did not answer in time")
+	errGameProcessSlow    = errors.New("game process did not answer before the tick deadline")
+)
+
+// gameProcess is a game running as a separate process, restarted whenever it exits or hangs
+type gameProcess struct {
+	gameID   GameID
+	settings GameProcessConfig
+	logger   *slog.Logger
+
+	// The running process and the client calling it, nil while it is being restarted
+	cmd    *exec.Cmd
+	client *rpc.Client
+	mux    sync.Mutex
+
+	// Closed to stop the process, and once it has exited for the last time
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startGameProcess runs a game's process and keeps it running until it is stopped
+func startGameProcess(gameID GameID, settings GameProcessConfig) (*gameProcess, error) {
+	process := &gameProcess{
+		gameID:   gameID,
+		settings: settings,
+		logger:   Logger.With("game_id", gameID),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	exited, err := process.start()
+	if err != nil {
+		return nil, err
+	}
+	go process.supervise(exited)
+	return process, nil
+}
+
+// start runs the process and connects to it, the channel receives the result of the process once it exits
+func (process *gameProcess) start() (<-chan error, error) {
+	cmd := exec.Command(process.settings.Command[0], process.settings.Command[1:]...)
+	cmd.Stderr = &processLogWriter{logger: process.logger}
+
+	var conn io.ReadWriteCloser
+	var err error
+	if process.settings.Transport == transportUnix {
+		conn, err = startUnixProcess(cmd)
+	} else {
+		conn, err = startStdioProcess(cmd)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	process.mux.Lock()
+	process.cmd = cmd
+	process.client = jsonrpc.NewClient(conn)
+	process.mux.Unlock()
+	process.logger.Info("game process started", "pid", cmd.Process.Pid)
+	return exited, nil
+}
+
+// startStdioProcess starts the process, calling it over its stdin and stdout
+func startStdioProcess(cmd *exec.Cmd) (io.ReadWriteCloser, error) {
+	stdinReader, stdinWriter, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		stdinReader.Close()
+		stdinWriter.Close()
+		return nil, err
+	}
+	cmd.Stdin = stdinReader
+	cmd.Stdout = stdoutWriter
+
+	err = cmd.Start()
+
+	// The process has its own copies of its ends of the pipes
+	stdinReader.Close()
+	stdoutWriter.Close()
+	if err != nil {
+		stdinWriter.Close()
+		stdoutReader.Close()
+		return nil, err
+	}
+	return &pipeConn{Reader: stdoutReader, Writer: stdinWriter}, nil
+}
+
+// startUnixProcess starts the process, calling it over a Unix socket it connects to
+func startUnixProcess(cmd *exec.Cmd) (io.ReadWriteCloser, error) {
+	dir, err := os.MkdirTemp("", "game-sharing-")
+	if err != nil {
+		return nil, err
+	}
+	// The connection outlives the socket file
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "game.sock")
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	defer listener.Close()
+
+	cmd.Env = append(os.Environ(), sdk.SocketEnv+"="+path)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	listener.SetDeadline(time.Now().Add(gameProcessStartTimeout))
+	conn, err := listener.Accept()
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// supervise restarts the process whenever it exits, waiting longer between restarts while it keeps exiting
+func (process *gameProcess) supervise(exited <-chan error) {
+	defer close(process.done)
+
+	delay := minRestartDelay
+	for {
+		startedOn := time.Now()
+		err := <-exited
+		process.disconnect()
+
+		select {
+		case <-process.stop:
+			process.logger.Info("game process stopped")
+			return
+		default:
+		}
+		if time.Since(startedOn) > stableProcessDuration {
+			delay = minRestartDelay
+		}
+		process.logger.Error("game process exited, restarting it", "error", err, "delay", delay)
+
+		for {
+			select {
+			case <-process.stop:
+				return
+			case <-time.After(delay):
+			}
+			delay = min(delay*2, process.settings.MaxRestartDelay)
+			gameProcessRestartsCounter.WithLabelValues(process.gameID).Inc()
+
+			exited, err = process.start()
+			if err == nil {
+				break
+			}
+			process.logger.Error("restarting game process failed", "error", err, "delay", delay)
+		}
+	}
+}
+
+// pendingCall is a call to a game process, its error is set once done is closed
+type pendingCall struct {
+	done chan struct{}
+	err  error
+}
+
+// call calls a method of the game, killing the process if it does not answer within the call timeout
+func (process *gameProcess) call(method string, args interface{}, reply interface{}) error {
+	pending := process.goCall(method, args, reply)
+	<-pending.done
+	return pending.err
+}
+
+// goCall starts calling a method of the game, the reply is only written before done is closed
+// The process is killed if it does not answer within the call timeout, even if the caller stopped waiting
+func (process *gameProcess) goCall(method string, args interface{}, reply interface{}) *pendingCall {
+	pending := &pendingCall{done: make(chan struct{})}
+
+	process.mux.Lock()
+	client := process.client
+	process.mux.Unlock()
+	if client == nil {
+		pending.err = errGameProcessDown
+		gameProcessCallErrorsCounter.WithLabelValues(process.gameID, method).Inc()
+		close(pending.done)
+		return pending
+	}
+
+	call := client.Go("Game."+method, args, reply, make(chan *rpc.Call, 1))
+	go func() {
+		defer close(pending.done)
+
+		timeout := time.NewTimer(process.settings.CallTimeout)
+		defer timeout.Stop()
+
+		select {
+		case <-call.Done:
+			pending.err = call.Error
+		case <-timeout.C:
+			// The process is restarted by its supervisor once it has exited
+			process.logger.Error("game process did not answer in time, killing it", "method", method, "timeout", process.settings.CallTimeout)
+			process.kill()
+			pending.err = errGameProcessTimeout
+		}
+		if pending.err != nil {
+			gameProcessCallErrorsCounter.WithLabelValues(process.gameID, method).Inc()
+		}
+	}()
+	return pending
+}
+
+// Running returns whether the process is running and can be called
+func (process *gameProcess) Running() bool {
+	process.mux.Lock()
+	defer process.mux.Unlock()
+
+	return process.client != nil
+}
+
+// Stop ends the process, killing it if it does not exit once its connection is closed
+func (process *gameProcess) Stop() {
+	close(process.stop)
+	process.disconnect()
+
+	select {
+	case <-process.done:
+	case <-time.After(gameProcessStopTimeout):
+		process.kill()
+		<-process.done
+	}
+}
+
+// disconnect closes the connection to the process, which exits when it sees it closed
+func (process *gameProcess) disconnect() {
+	process.mux.Lock()
+	defer process.mux.Unlock()
+
+	if process.client != nil {
+		process.client.Close()
+		process.client = nil
+	}
+}
+
+// kill ends the process immediately
+func (process *gameProcess) kill() {
+	process.mux.Lock()
+	defer process.mux.Unlock()
+
+	if process.cmd != nil {
+		process.cmd.Process.Kill()
+	}
+}
+
+// pipeConn joins the pipes to and from a process into a connection
+type pipeConn struct {
+	io.Reader
+	io.Writer
+}
+
+func (conn *pipeConn) Close() error {
+	return errors.Join(conn.Reader.(io.Closer).Close(), conn.Writer.(io.Closer).Close())
+}
+
+// processLogWriter logs each line a game process writes to stderr
+type processLogWriter struct {
+	logger  *slog.Logger
+	partial string
+}
+
+func (writer *processLogWriter) Write(p []byte) (int, error) {
+	lines := strings.Split(writer.partial+string(p), "\n")
+	writer.partial = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		if line = strings.TrimSpace(line); line != "" {
+			writer.logger.Info("game process output", "line", line)
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,346 @@
+package platform
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/gorilla/mux"
+)
+
+// Limits on the entries of the catalogue
+const (
+	maxGameNameLength        = 100
+	maxGameDescriptionLength = 1000
+	maxGameGenreLength       = 50
+	maxGameControlsLength    = 500
+	maxGameVersionLength     = 32
+	maxGamePlayers           = 1000
+)
+
+// Limits on uploaded thumbnails
+const (
+	maxThumbnailBytes = 1 << 20
+	maxThumbnailSize  = 1024
+)
+
+// validGameID matches the IDs new games may have, which must be safe to use in paths and keys
+var validGameID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// gameFields is the body of a request to create or change a game, omitted fields are unchanged
+type gameFields struct {
+	ID          *GameID `json:"id"`
+	ImageID     *string `json:"imageID"`
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Genre       *string `json:"genre"`
+	MinPlayers  *int    `json:"minPlayers"`
+	MaxPlayers  *int    `json:"maxPlayers"`
+	Controls    *string `json:"controls"`
+	Version     *string `json:"version"`
+	Enabled     *bool   `json:"enabled"`
+}
+
+// gameError is a problem with a catalogue entry, reported to the client with its status and code
+type gameError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (err *gameError) Error() string {
+	return err.message
+}
+
+// ListCatalogue returns every game in the catalogue, including disabled ones, optionally searched and filtered
+func ListCatalogue(w http.ResponseWriter, r *http.Request) {
+	filter, message := parseGameFilter(r)
+	if message != "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, message)
+		return
+	}
+	filter.IncludeDisabled = true
+	writeJSON(w, http.StatusOK, CatalogueGames(filter))
+}
+
+// GetCatalogueGame returns a game in the catalogue, whether or not it is enabled
+func GetCatalogueGame(w http.ResponseWriter, r *http.Request) {
+	game, ok := CatalogueGame(mux.Vars(r)["gameID"])
+	if !ok {
+		writeError(w, http.StatusNotFound, CodeGameNotFound, "Game ID is not in the catalogue.")
+		return
+	}
+	writeJSON(w, http.StatusOK, game)
+}
+
+// CreateGame adds a game to the catalogue, disabled unless enabled is given
+func CreateGame(w http.ResponseWriter, r *http.Request) {
+	fields, ok := readGameFields(w, r)
+	if !ok {
+		return
+	}
+	if fields.ID == nil || !validGameID.MatchString(*fields.ID) {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "The id must be 1 to 64 letters, digits, dashes or underscores.")
+		return
+	}
+	logger := annotateRequest(r, "game_id", *fields.ID)
+
+	game := Game{ID: *fields.ID}
+	fields.apply(&game)
+	if err := checkGame(&game); err != nil {
+		writeGameError(w, r, err)
+		return
+	}
+
+	game, err := AddCatalogueGame(game)
+	if err != nil {
+		writeGameError(w, r, err)
+		return
+	}
+	logger.Info("game added to the catalogue")
+
+	w.Header().Set("Location", "/v1/admin/games/"+game.ID)
+	writeJSON(w, http.StatusCreated, game)
+}
+
+// UpdateGame changes the metadata of a game in the catalogue, or enables or disables it
+func UpdateGame(w http.ResponseWriter, r *http.Request) {
+	gameID := mux.Vars(r)["gameID"]
+	logger := annotateRequest(r, "game_id", gameID)
+
+	fields, ok := readGameFields(w, r)
+	if !ok {
+		return
+	}
+	if fields.ID != nil && *fields.ID != gameID {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "The id of a game cannot be changed.")
+		return
+	}
+
+	game, err := UpdateCatalogueGame(gameID, func(game *Game) error {
+		fields.apply(game)
+		return checkGame(game)
+	})
+	if err != nil {
+		writeGameError(w, r, err)
+		return
+	}
+	logger.Info("game updated in the catalogue", "enabled", game.Enabled)
+
+	writeJSON(w, http.StatusOK, game)
+}
+
+// DeleteGame removes a game and its thumbnail from the catalogue
+// Saved states of the game are kept, so adding the game again makes them available
+func DeleteGame(w http.ResponseWriter, r *http.Request) {
+	gameID := mux.Vars(r)["gameID"]
+	logger := annotateRequest(r, "game_id", gameID)
+
+	for _, hub := range LiveHubs() {
+		if hub.server.GetGameID() == gameID {
+			writeError(w, http.StatusConflict, CodeGameInUse, "The game has live game sessions, disable it and wait for them to end first.")
+			return
+		}
+	}
+
+	if err := RemoveCatalogueGame(gameID); err != nil {
+		writeGameError(w, r, err)
+		return
+	}
+	logger.Info("game removed from the catalogue")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UploadGameThumbnail replaces the thumbnail of a game with the PNG, JPEG or GIF image in the body
+func UploadGameThumbnail(w http.ResponseWriter, r *http.Request) {
+	gameID := mux.Vars(r)["gameID"]
+	logger := annotateRequest(r, "game_id", gameID)
+
+	if _, ok := CatalogueGame(gameID); !ok {
+		writeError(w, http.StatusNotFound, CodeGameNotFound, "Game ID is not in the catalogue.")
+		return
+	}
+
+	thumbnail, err := io.ReadAll(io.LimitReader(r.Body, maxThumbnailBytes+1))
+	if err != nil || len(thumbnail) > maxThumbnailBytes {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("The thumbnail must be at most %d bytes.", maxThumbnailBytes))
+		return
+	}
+
+	// Check the image is what it claims to be before serving it to browsers
+	config, format, err := image.DecodeConfig(bytes.NewReader(thumbnail))
+	contentType := "image/" + format
+	declaredType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || contentType != declaredType {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "The body must be a PNG, JPEG or GIF image matching the Content-Type.")
+		return
+	}
+	if config.Width > maxThumbnailSize || config.Height > maxThumbnailSize {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("The thumbnail must be at most %dx%d pixels.", maxThumbnailSize, maxThumbnailSize))
+		return
+	}
+
+	if err := SaveGameThumbnail(gameID, contentType, thumbnail); err != nil {
+		writeGameError(w, r, err)
+		return
+	}
+	game, err := UpdateCatalogueGame(gameID, func(game *Game) error {
+		game.Thumbnail = true
+		return nil
+	})
+	if err != nil {
+		writeGameError(w, r, err)
+		return
+	}
+	logger.Info("game thumbnail uploaded", "bytes", len(thumbnail))
+
+	writeJSON(w, http.StatusOK, game)
+}
+
+// GetGameThumbnail returns the uploaded thumbnail of a game
+func GetGameThumbnail(w http.ResponseWriter, r *http.Request) {
+	gameID := mux.Vars(r)["gameID"]
+	if !gameExists(w, gameID) {
+		return
+	}
+
+	contentType, thumbnail, err := LoadGameThumbnail(gameID)
+	if err == redis.ErrNil {
+		writeError(w, http.StatusNotFound, CodeThumbnailNotFound, "The game has no thumbnail.")
+		return
+	} else if err != nil {
+		requestLogger(r).Error("reading game thumbnail failed", "error", err)
+		writeError(w, http.StatusInternalServerError, CodeDatabaseError, "Database error encountered while reading the thumbnail.")
+		return
+	}
+
+	// Thumbnails can be replaced, so browsers only keep them for a while
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	w.Write(thumbnail)
+}
+
+// readGameFields decodes and validates the body of a request to create or change a game
+func readGameFields(w http.ResponseWriter, r *http.Request) (*gameFields, bool) {
+	fields := &gameFields{}
+	decoder := json.NewDecoder(io.LimitReader(r.Body, 64*1024))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(fields); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "The request body must be a JSON object with the fields of a game.")
+		return nil, false
+	}
+	if message := fields.validate(); message != "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, message)
+		return nil, false
+	}
+	return fields, true
+}
+
+// writeGameError replies with the status and code matching an error from the catalogue
+func writeGameError(w http.ResponseWriter, r *http.Request, err error) {
+	var problem *gameError
+	switch {
+	case errors.As(err, &problem):
+		writeError(w, problem.status, problem.code, problem.message)
+	case errors.Is(err, errGameNotInCatalogue):
+		writeError(w, http.StatusNotFound, CodeGameNotFound, "Game ID is not in the catalogue.")
+	case errors.Is(err, errGameExists):
+		writeError(w, http.StatusConflict, CodeGameExists, "Game ID is already in the catalogue.")
+	default:
+		requestLogger(r).Error("updating the catalogue failed", "error", err)
+		writeError(w, http.StatusInternalServerError, CodeDatabaseError, "Database error encountered while updating the catalogue.")
+	}
+}
+
+// checkGame returns a gameError if the game's fields do not make sense together
+func checkGame(game *Game) error {
+	if game.Name == "" {
+		return &gameError{http.StatusBadRequest, CodeInvalidRequest, "The name is required."}
+	}
+	if game.MaxPlayers > 0 && game.MinPlayers > game.MaxPlayers {
+		return &gameError{http.StatusBadRequest, CodeInvalidRequest, "The minPlayers must not be more than the maxPlayers."}
+	}
+
+	// Games are only playable once their code is part of the service
+	if _, ok := GameServerMap[game.ID]; game.Enabled && !ok {
+		return &gameError{http.StatusConflict, CodeNoGameServer, "The game cannot be enabled because no game server is registered for it."}
+	}
+	return nil
+}
+
+// validate tidies the fields, returning a message describing the first invalid one
+func (fields *gameFields) validate() string {
+	for _, text := range []struct {
+		name  string
+		value *string
+		limit int
+	}{
+		{"imageID", fields.ImageID, maxGameNameLength},
+		{"name", fields.Name, maxGameNameLength},
+		{"description", fields.Description, maxGameDescriptionLength},
+		{"genre", fields.Genre, maxGameGenreLength},
+		{"controls", fields.Controls, maxGameControlsLength},
+		{"version", fields.Version, maxGameVersionLength},
+	} {
+		if text.value == nil {
+			continue
+		}
+		*text.value = strings.TrimSpace(*text.value)
+		if utf8.RuneCountInString(*text.value) > text.limit {
+			return fmt.Sprintf("The %s must be at most %d characters.", text.name, text.limit)
+		}
+	}
+
+	for _, count := range []struct {
+		name  string
+		value *int
+	}{{"minPlayers", fields.MinPlayers}, {"maxPlayers", fields.MaxPlayers}} {
+		if count.value != nil && (*count.value < 0 || *count.value > maxGamePlayers) {
+			return fmt.Sprintf("The %s must be between 0 and %d.", count.name, maxGamePlayers)
+		}
+	}
+
+	return ""
+}
+
+// apply sets the fields that were given on the game
+func (fields *gameFields) apply(game *Game) {
+	for _, text := range []struct {
+		value  *string
+		target *string
+	}{
+		{fields.ImageID, &game.ImageNumber},
+		{fields.Name, &game.Name},
+		{fields.Description, &game.Description},
+		{fields.Genre, &game.Genre},
+		{fields.Controls, &game.Controls},
+		{fields.Version, &game.Version},
+	} {
+		if text.value != nil {
+			*text.target = *text.value
+		}
+	}
+	if fields.MinPlayers != nil {
+		game.MinPlayers = *fields.MinPlayers
+	}
+	if fields.MaxPlayers != nil {
+		game.MaxPlayers = *fields.MaxPlayers
+	}
+	if fields.Enabled != nil {
+		game.Enabled = *fields.Enabled
+	}
+}
@@ -0,0 +1,94 @@
+package platform
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// HealthCheck is the model for the result of checking one dependency
+type HealthCheck struct {
+	Healthy bool   `json:"healthy"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// HealthReport is the model for health and readiness responses
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks"`
+}
+
+// checkDependencies reports on Redis connectivity, game server registration and hub load
+func checkDependencies() map[string]HealthCheck {
+	checks := make(map[string]HealthCheck)
+
+	// Redis must answer a PING
+	conn := DatabasePool.Get()
+	defer conn.Close()
+	if err := Ping(conn); err != nil {
+		checks["redis"] = HealthCheck{Healthy: false, Detail: err.Error()}
+	} else {
+		checks["redis"] = HealthCheck{Healthy: true}
+	}
+
+	// Every enabled game in the catalogue must have a game server
+	gameServers := HealthCheck{Healthy: true, Detail: strconv.Itoa(len(GameServerMap)) + " game servers registered"}
+	for _, game := range CatalogueGames(GameFilter{}) {
+		if _, ok := GameServerMap[game.ID]; !ok {
+			gameServers = HealthCheck{Healthy: false, Detail: "no game server registered for game " + game.ID}
+			break
+		}
+	}
+	if len(GameServerMap) == 0 {
+		gameServers = HealthCheck{Healthy: false, Detail: "no game servers registered"}
+	}
+	checks["gameServers"] = gameServers
+
+	// Game processes that exited are restarted, their games cannot be played until they are
+	gameProcessCheck := HealthCheck{Healthy: true, Detail: strconv.Itoa(len(gameProcesses)) + " game processes running"}
+	for _, process := range gameProcesses {
+		if !process.Running() {
+			gameProcessCheck = HealthCheck{Healthy: false, Detail: "game process for game " + process.gameID + " is not running"}
+			break
+		}
+	}
+	checks["gameProcesses"] = gameProcessCheck
+
+	// The number of live game sessions must be within the limit
+	liveHubs := len(LiveHubs())
+	hubLoad := HealthCheck{Healthy: true, Detail: strconv.Itoa(liveHubs) + " live game sessions"}
+	if maxHubs := AppConfig.Hubs.MaxHubs; maxHubs > 0 {
+		hubLoad.Detail += " of " + strconv.Itoa(maxHubs)
+		hubLoad.Healthy = liveHubs < maxHubs
+	}
+	checks["hubs"] = hubLoad
+
+	return checks
+}
+
+// writeHealthReport writes the checks, failing with 503 if any required check is unhealthy
+func writeHealthReport(w http.ResponseWriter, checks map[string]HealthCheck, required ...string) {
+	report := HealthReport{Status: "ok", Checks: checks}
+	status := http.StatusOK
+	for _, name := range required {
+		if !checks[name].Healthy {
+			report.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetHealth reports whether the process is alive, along with the state of its dependencies
+func GetHealth(w http.ResponseWriter, r *http.Request) {
+	writeHealthReport(w, checkDependencies())
+}
+
+// GetReadiness reports whether the service can take traffic
+// It fails when the store is unreachable, no games can be served, or the hub limit is reached
+func GetReadiness(w http.ResponseWriter, r *http.Request) {
+	writeHealthReport(w, checkDependencies(), "redis", "gameServers", "hubs")
+}
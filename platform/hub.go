@@ -0,0 +1,504 @@
+package platform
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/game-sharing/service/sdk"
+)
+
+// pausedPollInterval is how often a paused game loop checks whether it has been resumed
+const pausedPollInterval = 50 * time.Millisecond
+
+// Hub represents a live game being played by one or more players
+type Hub struct {
+	// The state ID the live game session is accessed by
+	id StateID
+
+	// The user who started the live game session
+	owner UserID
+
+	// Game server that processes the game state
+	server GameServer
+
+	// The current game state
+	state *SessionState
+
+	// Registered clients
+	clients map[*Client]bool
+
+	// Inbound messages from clients
+	broadcast chan ClientInput
+
+	// Register requests from the clients
+	register chan *Client
+
+	// Unregister requests from clients
+	unregister chan *Client
+
+	// Inbound display data from game server
+	displayData chan sdk.DisplayData
+
+	// Requests for the latency statistics of the registered clients
+	statsRequest chan chan []LatencyReport
+
+	// Stop requests carrying the reason given to clients
+	stop chan string
+
+	// Closed once the hub has stopped processing I/O
+	quit chan struct{}
+
+	// Closed once the game loop has exited
+	loopDone chan struct{}
+
+	// Requests for the game loop to checkpoint the game state
+	checkpointRequest chan struct{}
+
+	// Requests for the game loop to encode the game state for saving
+	snapshotRequest chan chan StateSnapshot
+
+	// Operator actions, run by the goroutine that owns the clients
+	control chan func()
+
+	// Whether a checkpoint has been stored, only accessed by the game loop
+	checkpointed bool
+
+	// Users that have played in the hub, who are credited with its saves
+	players    map[UserID]bool
+	playersMux sync.Mutex
+
+	// Users the owner has invited to play in the hub, guarded by playersMux
+	invited map[UserID]bool
+
+	// Game input data
+	gameInput sdk.InputData
+
+	// How clients that cannot keep up are treated
+	policy BackpressurePolicy
+
+	// Time between ticks of the game loop
+	tickInterval time.Duration
+
+	// When the hub was started
+	startedOn time.Time
+
+	// Ticks of the game loop so far, and when an input was last accepted in Unix nanoseconds
+	ticks     atomic.Uint64
+	lastInput atomic.Int64
+
+	// Set while an operator has paused the game, which then stops ticking and ignores inputs
+	paused atomic.Bool
+
+	// Set once the hub is being ended, so it is only saved once
+	ending atomic.Bool
+
+	// Logger carrying the game and state IDs
+	logger *slog.Logger
+}
+
+func runGameLoop(hub *Hub) {
+	defer close(hub.loopDone)
+
+	// Checkpoint the game state periodically
+	var checkpointTicks <-chan time.Time
+	if checkpointInterval := AppConfig.Hubs.CheckpointInterval; checkpointInterval > 0 {
+		checkpointTicker := time.NewTicker(checkpointInterval)
+		defer checkpointTicker.Stop()
+		checkpointTicks = checkpointTicker.C
+	}
+
+	tickDuration := tickDurationHistogram.WithLabelValues(hub.server.GetGameID())
+	for {
+		// Hold the game still while it is paused, it can still be checkpointed
+		if hub.paused.Load() {
+			select {
+			case <-hub.quit:
+				return
+			case <-hub.checkpointRequest:
+				hub.checkpoint()
+			case reply := <-hub.snapshotRequest:
+				reply <- hub.snapshot()
+			case <-time.After(pausedPollInterval):
+			}
+			continue
+		}
+
+		tickStart := time.Now()
+		hub.server.ProcessState(hub.state.State, hub.gameInput)
+		displayData := hub.state.GetDisplayData()
+		tickDuration.Observe(time.Since(tickStart).Seconds())
+		hub.ticks.Add(1)
+
+		select {
+		case hub.displayData <- displayData:
+		case <-hub.quit:
+			return
+		}
+		hub.gameInput = nil
+
+		// Checkpoint and save between ticks, when the game state is consistent
+		select {
+		case <-checkpointTicks:
+			hub.checkpoint()
+		case <-hub.checkpointRequest:
+			hub.checkpoint()
+		case reply := <-hub.snapshotRequest:
+			reply <- hub.snapshot()
+		default:
+		}
+
+		time.Sleep(hub.tickInterval) // probably some other way to make a consistent loop
+	}
+}
+
+// ClientInput is an input message along with the client that sent it
+type ClientInput struct {
+	client *Client
+	data   sdk.InputData
+}
+
+// HubKey identifies a live game session, state IDs are only unique within a game
+type HubKey struct {
+	GameID  GameID
+	StateID StateID
+}
+
+// hubsMux guards Hubs, which is read by request handlers and changed as hubs start and end
+var hubsMux sync.RWMutex
+
+// endingHubs counts the hubs being ended, which shutdown waits for so their saves are not cut short
+var endingHubs sync.WaitGroup
+
+// GetHub returns the live game session of the game with the state ID
+func GetHub(gameID GameID, stateID StateID) (*Hub, bool) {
+	hubsMux.RLock()
+	defer hubsMux.RUnlock()
+
+	hub, ok := Hubs[HubKey{GameID: gameID, StateID: stateID}]
+	return hub, ok
+}
+
+// AddHub makes a hub available as a live game session
+// It returns false without adding the hub if another one has its ID, which is never replaced
+func AddHub(hub *Hub) bool {
+	hubsMux.Lock()
+	defer hubsMux.Unlock()
+
+	if _, taken := Hubs[hub.key()]; taken {
+		return false
+	}
+	Hubs[hub.key()] = hub
+	liveHubsGauge.Inc()
+	return true
+}
+
+// RemoveHub removes a live game session that has ended
+func RemoveHub(gameID GameID, stateID StateID) {
+	key := HubKey{GameID: gameID, StateID: stateID}
+	hubsMux.Lock()
+	_, ok := Hubs[key]
+	delete(Hubs, key)
+	hubsMux.Unlock()
+	if ok {
+		liveHubsGauge.Dec()
+	}
+}
+
+// key returns the key the hub is stored under in Hubs
+func (hub *Hub) key() HubKey {
+	return HubKey{GameID: hub.server.GetGameID(), StateID: hub.id}
+}
+
+// LiveHubs returns every live game session
+func LiveHubs() []*Hub {
+	hubsMux.RLock()
+	defer hubsMux.RUnlock()
+
+	hubs := []*Hub{}
+	for _, hub := range Hubs {
+		hubs = append(hubs, hub)
+	}
+	return hubs
+}
+
+// NewHub returns a new Hub for the live game
+func NewHub(server GameServer, owner UserID) *Hub {
+	newHub := startHub(server, server.NewState(), owner)
+	newHub.logger.Info("hub created")
+	return newHub
+}
+
+// LoadHub returns a new Hub with a given state, under a new state ID
+func LoadHub(server GameServer, stateID StateID, owner UserID) (*Hub, error) {
+	state, err := server.LoadState(stateID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each load is a live game session of its own, so the saved state can be loaded again
+	state.id = server.NewStateID()
+	newHub := startHub(server, state, owner)
+	newHub.logger.Info("hub loaded")
+	return newHub, nil
+}
+
+// startHub creates a Hub for the state and starts its game loop
+func startHub(server GameServer, state *SessionState, owner UserID, players ...UserID) *Hub {
+	newHub := &Hub{
+		id:           state.GetID(),
+		owner:        owner,
+		server:       server,
+		state:        state,
+		broadcast:    make(chan ClientInput),
+		register:     make(chan *Client),
+		unregister:   make(chan *Client),
+		clients:      make(map[*Client]bool),
+		displayData:  make(chan sdk.DisplayData),
+		statsRequest: make(chan chan []LatencyReport),
+		stop:         make(chan string),
+		quit:         make(chan struct{}),
+		loopDone:     make(chan struct{}),
+
+		checkpointRequest: make(chan struct{}, 1),
+		snapshotRequest:   make(chan chan StateSnapshot),
+		control:           make(chan func()),
+		players:           make(map[UserID]bool),
+		invited:           make(map[UserID]bool),
+		policy:            server.GetBackpressurePolicy(),
+		tickInterval:      AppConfig.GameSettings(server.GetGameID()).TickInterval,
+		logger:            Logger.With("game_id", server.GetGameID(), "state_id", state.GetID()),
+		startedOn:         time.Now(),
+	}
+	newHub.players[owner] = true
+	for _, userID := range players {
+		newHub.players[userID] = true
+	}
+	go runGameLoop(newHub)
+	return newHub
+}
+
+func (hub *Hub) processIO() {
+	connectedClients := connectedClientsGauge.WithLabelValues(hub.server.GetGameID())
+	droppedInputs := droppedInputsCounter.WithLabelValues(hub.server.GetGameID())
+
+	// End the live game session once nobody has been connected for a while
+	emptySince := time.Now()
+	var idleChecks <-chan time.Time
+	if idleTimeout := AppConfig.Hubs.IdleTimeout; idleTimeout > 0 {
+		idleTicker := time.NewTicker(idleTimeout / 4)
+		defer idleTicker.Stop()
+		idleChecks = idleTicker.C
+	}
+
+	for {
+		select {
+		case client := <-hub.register:
+			// Register the client coming from the channel
+			if _, ok := hub.clients[client]; !ok {
+				connectedClients.Inc()
+				client.inputLimiter = AppConfig.RateLimits.Inputs.newLimiter()
+			}
+			hub.clients[client] = true
+			hub.addPlayer(client.userID)
+			hub.logger.Info("client registered", "user_id", client.userID, "conn_id", client.id)
+		case client := <-hub.unregister:
+			// Unregister the client and delete from the active list
+			if _, ok := hub.clients[client]; ok {
+				delete(hub.clients, client)
+				removeUserClient(client)
+				close(client.send)
+				connectedClients.Dec()
+				hub.logger.Info("client unregistered", "user_id", client.userID, "conn_id", client.id)
+
+				// Keep the player's progress if the process crashes after they leave
+				hub.requestCheckpoint()
+				if len(hub.clients) == 0 {
+					emptySince = time.Now()
+				}
+			}
+		case newInput := <-hub.broadcast:
+			// Drop inputs from clients sending faster than their limit
+			if limiter := newInput.client.inputLimiter; limiter != nil && !limiter.Allow() {
+				droppedInputs.Inc()
+				continue
+			}
+			// Inputs made while the game is paused would all apply at once when it resumes
+			if hub.paused.Load() {
+				continue
+			}
+			hub.lastInput.Store(time.Now().UnixNano())
+			for _, char := range newInput.data {
+				hub.gameInput = append(hub.gameInput, char)
+			}
+		case <-idleChecks:
+			if len(hub.clients) == 0 && time.Since(emptySince) > AppConfig.Hubs.IdleTimeout && !hub.ending.Load() {
+				go hub.End("Live game session ended after being idle, your game has been saved.")
+			}
+		case outputData := <-hub.displayData:
+			// Process each client
+			for client := range hub.clients {
+				if !hub.deliverFrame(client, outputData) {
+					// The client has been overloaded for too long, assume it is dead or stuck
+					close(client.send)
+					delete(hub.clients, client)
+					removeUserClient(client)
+					connectedClients.Dec()
+					droppedClientsCounter.WithLabelValues(hub.server.GetGameID()).Inc()
+					hub.logger.Warn("client dropped after sustained overload", "user_id", client.userID, "conn_id", client.id)
+				}
+			}
+		case reply := <-hub.statsRequest:
+			// Report the connection quality of each client
+			reports := []LatencyReport{}
+			for client := range hub.clients {
+				reports = append(reports, client.stats.report(client.userID))
+			}
+			reply <- reports
+		case action := <-hub.control:
+			action()
+		case reason := <-hub.stop:
+			// Disconnect every client with the reason and stop the game loop
+			for client := range hub.clients {
+				client.closeReason = reason
+				close(client.send)
+				delete(hub.clients, client)
+				removeUserClient(client)
+				connectedClients.Dec()
+			}
+			close(hub.quit)
+			hub.logger.Info("hub stopped", "reason", reason)
+			return
+		}
+	}
+}
+
+// Stop disconnects the hub's clients with the given reason and waits for its game loop to exit
+// The game state is no longer modified once Stop returns
+func (hub *Hub) Stop(reason string) {
+	select {
+	case hub.stop <- reason:
+	case <-hub.quit:
+	}
+	<-hub.loopDone
+}
+
+// End stops the live game session, saves it for its players and removes it
+// It returns false without doing anything if the hub is already being ended
+func (hub *Hub) End(reason string) bool {
+	// Counted before claiming the hub, so shutdown cannot fail to claim it and then miss it while waiting
+	endingHubs.Add(1)
+	defer endingHubs.Done()
+	if !hub.ending.CompareAndSwap(false, true) {
+		return false
+	}
+
+	hub.Stop(reason)
+	saveForPlayers(hub)
+
+	// The session is over, so it must not be restored on startup
+	if err := DeleteCheckpoint(hub.server.GetGameID(), hub.id); err != nil {
+		hub.logger.Error("deleting checkpoint failed", "error", err)
+	}
+	RemoveHub(hub.server.GetGameID(), hub.id)
+	hub.logger.Info("hub ended", "reason", reason)
+	return true
+}
+
+// sendInput passes an input from a client to the hub, returning false if the hub has stopped
+func (hub *Hub) sendInput(client *Client, data sdk.InputData) bool {
+	select {
+	case hub.broadcast <- ClientInput{client: client, data: data}:
+		return true
+	case <-hub.quit:
+		return false
+	}
+}
+
+// join registers a client with the hub, returning false if the hub has stopped
+func (hub *Hub) join(client *Client) bool {
+	select {
+	case hub.register <- client:
+		return true
+	case <-hub.quit:
+		return false
+	}
+}
+
+// leave unregisters a client from the hub if it is still running
+func (hub *Hub) leave(client *Client) {
+	select {
+	case hub.unregister <- client:
+	case <-hub.quit:
+	}
+}
+
+// addPlayer credits a user with playing in the hub
+func (hub *Hub) addPlayer(userID UserID) {
+	hub.playersMux.Lock()
+	hub.players[userID] = true
+	hub.playersMux.Unlock()
+}
+
+// Invite allows a user to play in the hub
+func (hub *Hub) Invite(userID UserID) {
+	hub.playersMux.Lock()
+	hub.invited[userID] = true
+	hub.playersMux.Unlock()
+}
+
+// Invited returns the users the owner has invited to play in the hub
+func (hub *Hub) Invited() []UserID {
+	hub.playersMux.Lock()
+	defer hub.playersMux.Unlock()
+
+	invited := []UserID{}
+	for userID := range hub.invited {
+		invited = append(invited, userID)
+	}
+	return invited
+}
+
+// IsMember returns whether a user has played in the hub or been invited to
+func (hub *Hub) IsMember(userID UserID) bool {
+	hub.playersMux.Lock()
+	defer hub.playersMux.Unlock()
+
+	return hub.players[userID] || hub.invited[userID]
+}
+
+// Players returns the users that have played in the hub
+func (hub *Hub) Players() []UserID {
+	hub.playersMux.Lock()
+	defer hub.playersMux.Unlock()
+
+	players := []UserID{}
+	for userID := range hub.players {
+		players = append(players, userID)
+	}
+	return players
+}
+
+// LatencyStats returns the latency statistics of the clients connected to the hub
+func (hub *Hub) LatencyStats() []LatencyReport {
+	reply := make(chan []LatencyReport)
+	select {
+	case hub.statsRequest <- reply:
+		return <-reply
+	case <-hub.quit:
+		return []LatencyReport{}
+	}
+}
+
+// run calls action on the goroutine that owns the clients, returning false if the hub has stopped
+func (hub *Hub) run(action func()) bool {
+	done := make(chan struct{})
+	select {
+	case hub.control <- func() { action(); close(done) }:
+		<-done
+		return true
+	case <-hub.quit:
+		return false
+	}
+}
@@ -0,0 +1,117 @@
+package platform
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ClientStats tracks the connection quality of a client
+type ClientStats struct {
+	mux sync.Mutex
+
+	// Smoothed round-trip time of ping/pong exchanges
+	rtt time.Duration
+
+	// Mean deviation between consecutive round-trip times
+	jitter time.Duration
+
+	// Latest round-trip time, which the next one is compared with for jitter
+	lastRTT time.Duration
+
+	// Time between an input arriving and the next frame being written
+	inputLatency time.Duration
+
+	// Number of round-trip samples taken
+	samples int
+
+	// Arrival time of the oldest input not yet followed by a frame
+	pendingInput time.Time
+}
+
+// LatencyReport is the model for a client's connection quality
+type LatencyReport struct {
+	UserID       UserID  `json:"userID"`
+	RTT          float64 `json:"rttMs"`
+	Jitter       float64 `json:"jitterMs"`
+	InputLatency float64 `json:"inputLatencyMs"`
+	Samples      int     `json:"samples"`
+	MessageType  string  `json:"type,omitempty"`
+}
+
+// newPingPayload returns the current time as ping application data
+func newPingPayload() []byte {
+	return []byte(strconv.FormatInt(time.Now().UnixNano(), 10))
+}
+
+// recordPong updates the round-trip time and jitter from a pong's application data
+func (stats *ClientStats) recordPong(appData string) {
+	sentAt, err := strconv.ParseInt(appData, 10, 64)
+	if err != nil {
+		// Not one of our pings (e.g. a browser sending an unsolicited pong)
+		return
+	}
+	sample := time.Since(time.Unix(0, sentAt))
+
+	stats.mux.Lock()
+	defer stats.mux.Unlock()
+
+	if stats.samples == 0 {
+		stats.rtt = sample
+	} else {
+		// Jitter as in RFC 3550, smoothed round-trip time as in RFC 6298
+		deviation := sample - stats.lastRTT
+		if deviation < 0 {
+			deviation = -deviation
+		}
+		stats.jitter += (deviation - stats.jitter) / 16
+		stats.rtt += (sample - stats.rtt) / 8
+	}
+	stats.lastRTT = sample
+	stats.samples++
+}
+
+// recordInput marks the arrival of an input from the player
+func (stats *ClientStats) recordInput(receivedAt time.Time) {
+	stats.mux.Lock()
+	if stats.pendingInput.IsZero() {
+		stats.pendingInput = receivedAt
+	}
+	stats.mux.Unlock()
+}
+
+// recordFrame measures the input-to-frame latency once a frame has been written
+func (stats *ClientStats) recordFrame(writtenAt time.Time) {
+	stats.mux.Lock()
+	if !stats.pendingInput.IsZero() {
+		stats.inputLatency = writtenAt.Sub(stats.pendingInput)
+		stats.pendingInput = time.Time{}
+	}
+	stats.mux.Unlock()
+}
+
+// report returns a snapshot of the statistics for the given user
+func (stats *ClientStats) report(userID UserID) LatencyReport {
+	stats.mux.Lock()
+	defer stats.mux.Unlock()
+
+	return LatencyReport{
+		UserID:       userID,
+		RTT:          durationToMilliseconds(stats.rtt),
+		Jitter:       durationToMilliseconds(stats.jitter),
+		InputLatency: durationToMilliseconds(stats.inputLatency),
+		Samples:      stats.samples,
+	}
+}
+
+// statsMessage returns the periodic stats message sent to the player
+func (stats *ClientStats) statsMessage(userID UserID) ([]byte, error) {
+	report := stats.report(userID)
+	report.MessageType = "stats"
+	return json.Marshal(report)
+}
+
+func durationToMilliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,118 @@
+package platform
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Logger is the structured logger shared by the whole service
+var Logger = slog.Default()
+
+type loggerKey struct{}
+
+// requestLogContext holds the logger of a request so handlers can add fields to it
+type requestLogContext struct {
+	logger *slog.Logger
+}
+
+// InitLogger configures Logger with the given level and format (text or json)
+func InitLogger(level string, format string) error {
+	var slogLevel slog.Level
+	if err := slogLevel.UnmarshalText([]byte(level)); err != nil {
+		return errors.New("invalid log level \"" + level + "\"")
+	}
+	options := &slog.HandlerOptions{Level: slogLevel}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, options)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	default:
+		return errors.New("invalid log format \"" + format + "\", expected text or json")
+	}
+
+	Logger = slog.New(handler)
+	slog.SetDefault(Logger)
+	return nil
+}
+
+// newCorrelationID returns a random identifier for requests and connections
+func newCorrelationID() string {
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(bytes)
+}
+
+// requestLogger returns the logger carrying the request's correlation fields
+func requestLogger(r *http.Request) *slog.Logger {
+	if logContext, ok := r.Context().Value(loggerKey{}).(*requestLogContext); ok {
+		return logContext.logger
+	}
+	return Logger
+}
+
+// annotateRequest adds fields to the request's logger, including its completion log
+func annotateRequest(r *http.Request, args ...any) *slog.Logger {
+	if logContext, ok := r.Context().Value(loggerKey{}).(*requestLogContext); ok {
+		logContext.logger = logContext.logger.With(args...)
+		return logContext.logger
+	}
+	return Logger.With(args...)
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+// Hijack lets WebSocket upgrades take over the connection
+func (recorder *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := recorder.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	recorder.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// LoggingMiddleware gives each request a correlation ID and logs its outcome
+func LoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" {
+			requestID = newCorrelationID()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+
+		logContext := &requestLogContext{logger: Logger.With("request_id", requestID)}
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), loggerKey{}, logContext)))
+
+		logContext.logger.Info("request handled",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", recorder.status,
+			"duration", time.Since(start))
+	})
+}
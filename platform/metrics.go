@@ -0,0 +1,80 @@
+package platform
+
+import (
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics exported on the /metrics endpoint
+var (
+	liveHubsGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "game_sharing_live_hubs",
+		Help: "Number of live game sessions.",
+	})
+
+	connectedClientsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "game_sharing_connected_clients",
+		Help: "Number of clients connected to live game sessions.",
+	}, []string{"game"})
+
+	tickDurationHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "game_sharing_tick_duration_seconds",
+		Help:    "Time taken by a game server to process one tick of a live game session.",
+		Buckets: prometheus.ExponentialBuckets(0.00001, 2, 16),
+	}, []string{"game"})
+
+	droppedClientsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "game_sharing_dropped_clients_total",
+		Help: "Number of clients dropped by hubs for being too slow.",
+	}, []string{"game"})
+
+	droppedInputsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "game_sharing_dropped_inputs_total",
+		Help: "Number of inputs dropped by hubs for exceeding the per-client input rate.",
+	}, []string{"game"})
+
+	redisDurationHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "game_sharing_redis_duration_seconds",
+		Help:    "Latency of Redis commands.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"command"})
+
+	stateOperationsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "game_sharing_state_operations_total",
+		Help: "Number of game states saved, loaded and deleted.",
+	}, []string{"game", "operation"})
+
+	gameProcessRestartsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "game_sharing_game_process_restarts_total",
+		Help: "Number of times game processes were restarted after exiting or hanging.",
+	}, []string{"game"})
+
+	gameProcessCallErrorsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "game_sharing_game_process_call_errors_total",
+		Help: "Number of calls to game processes that failed.",
+	}, []string{"game", "method"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		liveHubsGauge,
+		connectedClientsGauge,
+		tickDurationHistogram,
+		droppedClientsCounter,
+		droppedInputsCounter,
+		redisDurationHistogram,
+		stateOperationsCounter,
+		gameProcessRestartsCounter,
+		gameProcessCallErrorsCounter,
+	)
+}
+
+// doCommand sends a command to Redis and records its latency
+func doCommand(conn redis.Conn, command string, args ...interface{}) (interface{}, error) {
+	start := time.Now()
+	reply, err := conn.Do(command, args...)
+	redisDurationHistogram.WithLabelValues(command).Observe(time.Since(start).Seconds())
+	return reply, err
+}
@@ -0,0 +1,17 @@
+package platform
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec describes the RESTful API served by MainRouter
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// GetOpenAPI returns the OpenAPI document describing the API
+func GetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
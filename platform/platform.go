@@ -0,0 +1,186 @@
+// Package platform runs games written with the SDK as a service: it manages players, live game
+// sessions and their saved states, and serves the REST API and WebSocket connections
+package platform
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os/signal"
+	"syscall"
+
+	"github.com/game-sharing/service/sdk"
+	"github.com/gomodule/redigo/redis"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MainRouter handles the RESTful API endpoints
+var MainRouter *mux.Router
+
+// WSRouter handles the websocket connections
+var WSRouter *mux.Router
+
+// DatabasePool is the pool of connections to Redis
+var DatabasePool *redis.Pool
+
+// registeredGames holds the games the platform serves, in the order they were registered
+var registeredGames []registeredGame
+
+// registeredGame is a game along with the ID it has in the catalogue
+type registeredGame struct {
+	gameID GameID
+	game   sdk.Game
+}
+
+// RegisterGame adds a game to be served under its ID in the catalogue, it must be called before Run
+func RegisterGame(gameID GameID, game sdk.Game) {
+	registeredGames = append(registeredGames, registeredGame{gameID: gameID, game: game})
+}
+
+// Run starts the platform with the command line arguments, returning the exit code once it has shut down
+func Run(args []string) int {
+	// Load and validate the configuration
+	config, command, err := LoadConfig(args)
+	if err == flag.ErrHelp {
+		return 0
+	} else if err != nil {
+		Logger.Error("invalid configuration", "error", err)
+		return 2
+	}
+	AppConfig = config
+
+	// Initialize logging
+	if err := InitLogger(config.Logging.Level, config.Logging.Format); err != nil {
+		Logger.Error("invalid logging configuration", "error", err)
+		return 2
+	}
+
+	// Initialize rate limits and bearer tokens
+	InitRateLimits(config.RateLimits)
+	InitAuth()
+
+	// Initialize Redis database
+	DatabasePool = NewPool(config.Redis)
+	Logger.Info("Redis server started", "addr", config.Redis.Addr)
+
+	// Test Redis connection
+	conn := DatabasePool.Get()
+	defer conn.Close()
+	databaseErr := Ping(conn)
+	if databaseErr != nil {
+		Logger.Error("Redis ping failed, the service will not be ready until it is reachable", "error", databaseErr)
+	} else if len(command) == 0 {
+		// Move saved states still in the legacy JSON lists, lists not yet moved are moved when they are next used
+		go func() {
+			migrated, err := MigrateAllUserStates()
+			if err != nil {
+				Logger.Error("migrating legacy saved state lists failed", "error", err, "migrated", migrated)
+				return
+			}
+			Logger.Info("migrated legacy saved state lists", "migrated", migrated)
+		}()
+	}
+
+	// Initialize the catalogue, users, and game servers
+	if err := LoadCatalogue(); err != nil {
+		Logger.Error("loading the game catalogue failed, serving the default games", "error", err)
+	}
+	GameServerMap = make(map[GameID]GameServer)
+	Users = make(map[UserID]bool)
+	Hubs = make(map[HubKey]*Hub)
+	UserClients = make(map[UserID]*Client)
+	for serverID, registered := range registeredGames {
+		GameServerMap[registered.gameID] = NewServerLogic(registered.gameID, serverID, registered.game)
+	}
+
+	// Start the games configured to run as separate processes, after the live game sessions are saved they are stopped
+	startGameProcesses(len(registeredGames))
+	defer stopGameProcesses()
+
+	// Run a maintenance command given after the flags instead of serving
+	if len(command) > 0 {
+		return RunCommand(command)
+	}
+
+	// Restore the live game sessions that were running when the last process exited
+	if config.Hubs.Rehydrate {
+		if err := RehydrateHubs(); err != nil {
+			Logger.Error("restoring live game sessions failed", "error", err)
+		}
+	}
+
+	// Initialize router
+	MainRouter = mux.NewRouter()
+	WSRouter = mux.NewRouter()
+
+	// Simple file server
+	fileServer := http.FileServer(http.Dir(config.Server.StaticDir))
+	MainRouter.Handle("/", fileServer)
+
+	// Define RESTful endpoints, the unversioned routes are kept for existing clients
+	RegisterV1Routes(MainRouter)
+	MainRouter.HandleFunc("/games", GetGames).Methods("GET")
+	if config.Server.LegacyRoutes {
+		Logger.Warn("serving the unversioned routes, which trust the user ID in the path")
+		RegisterLegacyRoutes(MainRouter)
+	}
+
+	// Expose metrics and health checks for monitoring
+	MainRouter.Handle("/metrics", promhttp.Handler()).Methods("GET")
+	MainRouter.HandleFunc("/healthz", GetHealth).Methods("GET")
+	MainRouter.HandleFunc("/readyz", GetReadiness).Methods("GET")
+
+	// Describe the API for tooling
+	MainRouter.HandleFunc("/openapi.json", GetOpenAPI).Methods("GET")
+
+	// Log every request with a correlation ID
+	MainRouter.Use(LoggingMiddleware)
+	WSRouter.Use(LoggingMiddleware)
+
+	// Reply to unmatched requests with the same error body as the endpoints, middleware only runs on matched routes
+	for _, router := range []*mux.Router{MainRouter, WSRouter} {
+		router.NotFoundHandler = LoggingMiddleware(http.HandlerFunc(NotFound))
+		router.MethodNotAllowedHandler = LoggingMiddleware(http.HandlerFunc(MethodNotAllowed))
+	}
+
+	// Configure websocket route, sharing the REST router when serving on one address
+	InitWebSocket(config.WebSocket)
+	wsRoutes := WSRouter
+	if config.Server.Unified {
+		wsRoutes = MainRouter
+	}
+	if config.Server.LegacyRoutes {
+		wsRoutes.HandleFunc("/play/{id}/{userID}/{stateID}", HandleWebSocket)
+	}
+	wsRoutes.Handle("/v1/games/{gameID}/sessions/{sessionID}/play", RequireAuth(http.HandlerFunc(PlaySession)))
+
+	// Start the servers using the addresses specified and log errors
+	servers := newServers()
+	serverErrors := startServers(servers)
+
+	// Run until a server fails or the process is asked to stop
+	signals, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	select {
+	case err := <-serverErrors:
+		Logger.Error("ListenAndServe failed", "error", err)
+		return 1
+	case <-signals.Done():
+		Logger.Info("shutting down")
+	}
+
+	// Stop accepting connections and let in-flight requests finish
+	ctx, cancel := context.WithTimeout(context.Background(), config.Server.ShutdownTimeout)
+	defer cancel()
+	shutdownServers(ctx, servers)
+
+	// Save every live game session for its players before exiting
+	ShutdownHubs("Server is shutting down, your game has been saved.")
+	if !waitForConnections(ctx) {
+		Logger.Warn("timed out waiting for clients to disconnect")
+	}
+	Logger.Info("shutdown complete")
+	return 0
+}
@@ -0,0 +1,144 @@
+package platform
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"log/slog"
+	"net/http"
+
+	"github.com/game-sharing/service/sdk"
+	"github.com/gomodule/redigo/redis"
+	"github.com/gorilla/mux"
+)
+
+// maxPreviewSize is the largest width or height of a preview, larger renders are scaled down
+const maxPreviewSize = 256
+
+// errNoPreviewRenderer is returned for games whose server cannot draw previews
+var errNoPreviewRenderer = errors.New("game server does not render previews")
+
+// renderPreview draws display data with the game's renderer and encodes it as a PNG
+func renderPreview(server GameServer, display sdk.DisplayData) ([]byte, error) {
+	renderer, ok := server.GetGame().(sdk.PreviewRenderer)
+	if !ok {
+		return nil, errNoPreviewRenderer
+	}
+
+	img, err := renderer.RenderPreview(display)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := &bytes.Buffer{}
+	if err := png.Encode(encoded, shrinkPreview(img)); err != nil {
+		return nil, err
+	}
+	return encoded.Bytes(), nil
+}
+
+// shrinkPreview scales an image down to fit within maxPreviewSize, keeping its aspect ratio
+func shrinkPreview(img image.Image) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxPreviewSize && height <= maxPreviewSize {
+		return img
+	}
+
+	scaledWidth, scaledHeight := maxPreviewSize, maxPreviewSize
+	if width > height {
+		scaledHeight = max(1, height*maxPreviewSize/width)
+	} else {
+		scaledWidth = max(1, width*maxPreviewSize/height)
+	}
+
+	// Nearest neighbour keeps the hard edges of pixel displays
+	scaled := image.NewRGBA(image.Rect(0, 0, scaledWidth, scaledHeight))
+	for y := 0; y < scaledHeight; y++ {
+		for x := 0; x < scaledWidth; x++ {
+			scaled.Set(x, y, img.At(bounds.Min.X+x*width/scaledWidth, bounds.Min.Y+y*height/scaledHeight))
+		}
+	}
+	return scaled
+}
+
+// captureStatePreview stores a preview of the display data for a newly saved state, returning true if one was stored
+// The state is saved either way, so failures are only logged
+func captureStatePreview(logger *slog.Logger, gameID GameID, stateID StateID, display sdk.DisplayData) bool {
+	if display == nil {
+		return false
+	}
+
+	preview, err := renderPreview(GameServerMap[gameID], display)
+	if errors.Is(err, errNoPreviewRenderer) {
+		return false
+	} else if err != nil {
+		logger.Warn("rendering saved state preview failed", "error", err)
+		return false
+	}
+
+	if err := SaveStatePreview(gameID, stateID, preview); err != nil {
+		logger.Warn("storing saved state preview failed", "error", err)
+		return false
+	}
+	return true
+}
+
+// GetSavedStatePreview returns the PNG preview of one of the caller's saved states
+func GetSavedStatePreview(w http.ResponseWriter, r *http.Request) {
+	gameID, stateID, ok := savedStateParams(w, r)
+	if !ok {
+		return
+	}
+
+	writeStatePreview(w, r, gameID, authenticatedUser(r), stateID)
+}
+
+// GetStatePreview returns the PNG preview of a state in a user's list of saved states
+func GetStatePreview(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	gameID := params["id"]
+	userID := params["userID"]
+
+	if !errorCheck(w, r, gameID, userID) {
+		return
+	}
+
+	stateID := getValidStateID(w, r, params["stateID"])
+	if stateID == -1 {
+		return
+	}
+	annotateRequest(r, "game_id", gameID, "user_id", userID, "state_id", stateID)
+
+	writeStatePreview(w, r, gameID, userID, stateID)
+}
+
+// writeStatePreview replies with the preview of a state in a user's list of saved states
+func writeStatePreview(w http.ResponseWriter, r *http.Request, gameID GameID, userID UserID, stateID StateID) {
+	state, err := GetUserState(gameID, userID, stateID)
+	if err != nil {
+		writeUserStateError(w, r, err)
+		return
+	}
+	if !state.Preview {
+		writeError(w, http.StatusNotFound, CodePreviewNotFound, "The saved state has no preview.")
+		return
+	}
+
+	preview, err := LoadStatePreview(gameID, stateID)
+	if err == redis.ErrNil {
+		writeError(w, http.StatusNotFound, CodePreviewNotFound, "The saved state has no preview.")
+		return
+	} else if err != nil {
+		requestLogger(r).Error("reading saved state preview failed", "error", err)
+		writeError(w, http.StatusInternalServerError, CodeDatabaseError, "Database error encountered while reading the preview.")
+		return
+	}
+
+	// A saved state never changes, so neither does its preview
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	w.WriteHeader(http.StatusOK)
+	w.Write(preview)
+}
@@ -0,0 +1,178 @@
+package platform
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// How long an idle limiter is kept before its key is forgotten
+const limiterIdleTimeout = 10 * time.Minute
+
+// RateLimiter limits how often each user and each IP address can perform an action
+type RateLimiter struct {
+	limit    RateLimit
+	limiters map[string]*keyLimiter
+	mux      sync.Mutex
+}
+
+// keyLimiter is the token bucket of one user or IP address
+type keyLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Rate limiters for the REST endpoints, created by InitRateLimits
+var (
+	HubCreationLimiter *RateLimiter
+	SaveLimiter        *RateLimiter
+	LoginLimiter       *RateLimiter
+)
+
+// InitRateLimits creates the rate limiters and starts forgetting idle keys
+func InitRateLimits(config RateLimitConfig) {
+	HubCreationLimiter = NewRateLimiter(config.HubCreation)
+	SaveLimiter = NewRateLimiter(config.Saves)
+	LoginLimiter = NewRateLimiter(config.Logins)
+
+	go func() {
+		for range time.Tick(time.Minute) {
+			HubCreationLimiter.forgetIdle()
+			SaveLimiter.forgetIdle()
+			LoginLimiter.forgetIdle()
+		}
+	}()
+}
+
+// NewRateLimiter returns a limiter allowing limit.PerSecond actions per key, 0 for no limit
+func NewRateLimiter(limit RateLimit) *RateLimiter {
+	return &RateLimiter{
+		limit:    limit,
+		limiters: make(map[string]*keyLimiter),
+	}
+}
+
+// newLimiter returns a token bucket for the limit, or nil if there is no limit
+func (limit RateLimit) newLimiter() *rate.Limiter {
+	if limit.PerSecond <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(limit.PerSecond), limit.Burst)
+}
+
+// reserve takes a token for each key, returning how long to wait if any key is over its limit
+// No tokens are taken when the action is refused
+func (rateLimiter *RateLimiter) reserve(keys ...string) time.Duration {
+	if rateLimiter.limit.PerSecond <= 0 {
+		return 0
+	}
+
+	rateLimiter.mux.Lock()
+	defer rateLimiter.mux.Unlock()
+
+	now := time.Now()
+	reservations := []*rate.Reservation{}
+	wait := time.Duration(0)
+	for _, key := range keys {
+		entry, ok := rateLimiter.limiters[key]
+		if !ok {
+			entry = &keyLimiter{limiter: rateLimiter.limit.newLimiter()}
+			rateLimiter.limiters[key] = entry
+		}
+		entry.lastSeen = now
+
+		reservation := entry.limiter.ReserveN(now, 1)
+		reservations = append(reservations, reservation)
+		if delay := reservation.DelayFrom(now); delay > wait {
+			wait = delay
+		}
+	}
+
+	if wait > 0 {
+		for _, reservation := range reservations {
+			reservation.CancelAt(now)
+		}
+	}
+	return wait
+}
+
+// forgetIdle removes the limiters of keys that have not been seen in a while
+func (rateLimiter *RateLimiter) forgetIdle() {
+	rateLimiter.mux.Lock()
+	defer rateLimiter.mux.Unlock()
+
+	for key, entry := range rateLimiter.limiters {
+		if time.Since(entry.lastSeen) > limiterIdleTimeout {
+			delete(rateLimiter.limiters, key)
+		}
+	}
+}
+
+// clientIP returns the IP address the request came from
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// allowRequest applies the limiter to the user and the IP address of the request
+// Responds with 429 and a Retry-After hint if either is over its limit
+func allowRequest(w http.ResponseWriter, r *http.Request, rateLimiter *RateLimiter, userID UserID) bool {
+	wait := rateLimiter.reserve("user:"+userID, "ip:"+clientIP(r))
+	if wait == 0 {
+		return true
+	}
+
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	annotateRequest(r, "retry_after", retryAfter).Warn("request rate limited")
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	writeError(w, http.StatusTooManyRequests, CodeRateLimited, "Too many requests, retry in "+strconv.Itoa(retryAfter)+" seconds.")
+	return false
+}
+
+// pendingHubs counts the live game sessions each user is starting, which are not in Hubs yet
+var (
+	pendingHubs    = make(map[UserID]int)
+	pendingHubsMux sync.Mutex
+)
+
+// reserveNewHub reserves one of the user's live game sessions, unless they have reached the maximum
+// The returned function releases the reservation, and must be called once the hub has been added or was not started
+func reserveNewHub(w http.ResponseWriter, r *http.Request, userID UserID) (func(), bool) {
+	maxHubs := AppConfig.RateLimits.MaxHubsPerUser
+	if maxHubs <= 0 {
+		return func() {}, true
+	}
+
+	// Counting and reserving under one lock keeps concurrent requests from all seeing a free slot
+	pendingHubsMux.Lock()
+	owned := pendingHubs[userID]
+	for _, hub := range LiveHubs() {
+		if hub.owner == userID {
+			owned++
+		}
+	}
+	if owned < maxHubs {
+		pendingHubs[userID]++
+		pendingHubsMux.Unlock()
+		return func() {
+			pendingHubsMux.Lock()
+			if pendingHubs[userID]--; pendingHubs[userID] == 0 {
+				delete(pendingHubs, userID)
+			}
+			pendingHubsMux.Unlock()
+		}, true
+	}
+	pendingHubsMux.Unlock()
+
+	annotateRequest(r, "live_hubs", owned).Warn("live game session limit reached")
+	writeError(w, http.StatusTooManyRequests, CodeTooManySessions, "Too many live game sessions, save and leave one before starting another.")
+	return nil, false
+}
@@ -0,0 +1,79 @@
+package platform
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestMaxHubsPerUserConcurrently(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxHubs  int
+		requests int
+		want     int
+	}{
+		{"below the limit", 5, 3, 3},
+		{"at the limit", 2, 2, 2},
+		{"above the limit", 2, 20, 2},
+		{"no limit", 0, 10, 10},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			api := newTestAPI(t)
+			AppConfig.RateLimits.MaxHubsPerUser = test.maxHubs
+			token := api.login("alice")
+
+			var wg sync.WaitGroup
+			statuses := make(chan int, test.requests)
+			for i := 0; i < test.requests; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					status, _ := api.do("POST", "/v1/games/0/sessions", token, `{}`)
+					statuses <- status
+				}()
+			}
+			wg.Wait()
+			close(statuses)
+
+			created := 0
+			for status := range statuses {
+				switch status {
+				case http.StatusCreated:
+					created++
+				case http.StatusTooManyRequests:
+				default:
+					t.Errorf("got status %d, want %d or %d", status, http.StatusCreated, http.StatusTooManyRequests)
+				}
+			}
+			if created != test.want {
+				t.Errorf("created %d live game sessions, want %d", created, test.want)
+			}
+			if hubs := len(LiveHubs()); hubs != test.want {
+				t.Errorf("got %d live game sessions, want %d", hubs, test.want)
+			}
+			if len(pendingHubs) != 0 {
+				t.Errorf("reservations were not released: %v", pendingHubs)
+			}
+		})
+	}
+}
+
+func TestMaxHubsPerUserIsPerUser(t *testing.T) {
+	api := newTestAPI(t)
+	AppConfig.RateLimits.MaxHubsPerUser = 1
+	alice := api.login("alice")
+	bob := api.login("bob")
+
+	if status, body := api.do("POST", "/v1/games/0/sessions", alice, `{}`); status != http.StatusCreated {
+		t.Fatalf("got status %d, body %v", status, body)
+	}
+	status, body := api.do("POST", "/v1/games/0/sessions", alice, `{}`)
+	if status != http.StatusTooManyRequests || errorCode(body) != CodeTooManySessions {
+		t.Errorf("got status %d and code %q, want %d and %q", status, errorCode(body), http.StatusTooManyRequests, CodeTooManySessions)
+	}
+	if status, body := api.do("POST", "/v1/games/0/sessions", bob, `{}`); status != http.StatusCreated {
+		t.Errorf("another user was limited: status %d, body %v", status, body)
+	}
+}
@@ -0,0 +1,216 @@
+package platform
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"sort"
+	"time"
+
+	"github.com/game-sharing/service/sdk"
+)
+
+// errNoRemoteState is returned when saving a state its game process has not created yet
+var errNoRemoteState = errors.New("game process has not created the state")
+
+// gameProcesses are the games running as separate processes, stopped when the platform exits
+var gameProcesses []*gameProcess
+
+// remoteGame is a game running as a separate process, called over the game host protocol of the SDK
+// A call that fails leaves the state as it was, so a crashing game only pauses its own sessions
+type remoteGame struct {
+	process    *gameProcess
+	codec      sdk.StateCodec
+	migrations int
+	version    string
+}
+
+// previewingRemoteGame is a remote game that draws previews of its display data
+type previewingRemoteGame struct {
+	*remoteGame
+}
+
+// remoteState is the state of a remote game, kept encoded with the game's codec between calls
+type remoteState struct {
+	game    *remoteGame
+	state   []byte
+	display sdk.DisplayData
+
+	// The tick still running past its deadline and where its reply is written, nil if there is none
+	tick      *pendingCall
+	tickReply sdk.HostState
+
+	// Whether the last tick failed, so a failing process is logged once rather than every tick
+	failing bool
+}
+
+// startGameProcesses starts the games configured to run as separate processes and adds their game servers
+// Game servers are numbered from serverID, after the games built into the server
+func startGameProcesses(serverID GameServerID) {
+	gameIDs := []GameID{}
+	for gameID, settings := range AppConfig.Games {
+		if settings.Process != nil {
+			gameIDs = append(gameIDs, gameID)
+		}
+	}
+	sort.Strings(gameIDs)
+
+	for _, gameID := range gameIDs {
+		if _, ok := GameServerMap[gameID]; ok {
+			Logger.Error("game is built into the server, not starting its process", "game_id", gameID)
+			continue
+		}
+		game, err := startRemoteGame(gameID, *AppConfig.GameSettings(gameID).Process)
+		if err != nil {
+			Logger.Error("starting game process failed", "game_id", gameID, "error", err)
+			continue
+		}
+		GameServerMap[gameID] = NewServerLogic(gameID, serverID, game)
+		serverID++
+	}
+}
+
+// stopGameProcesses stops every game process once their live game sessions have been saved
+func stopGameProcesses() {
+	for _, process := range gameProcesses {
+		process.Stop()
+	}
+}
+
+// startRemoteGame starts a game's process and asks it how the game is played
+func startRemoteGame(gameID GameID, settings GameProcessConfig) (sdk.Game, error) {
+	process, err := startGameProcess(gameID, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	description := sdk.HostDescription{}
+	err = process.call("Describe", struct{}{}, &description)
+	codec, ok := sdk.LookupStateCodec(description.Codec)
+	if err == nil && description.ProtocolVersion != sdk.ProtocolVersion {
+		err = fmt.Errorf("game process speaks protocol version %d, not %d", description.ProtocolVersion, sdk.ProtocolVersion)
+	} else if err == nil && !ok {
+		err = fmt.Errorf("game process saves states with unknown codec %q", description.Codec)
+	}
+	if err != nil {
+		process.Stop()
+		return nil, err
+	}
+	gameProcesses = append(gameProcesses, process)
+
+	game := &remoteGame{process: process, codec: codec, migrations: description.Migrations, version: description.Version}
+	if description.Previews {
+		return &previewingRemoteGame{game}, nil
+	}
+	return game, nil
+}
+
+// NewState asks the process for the state of a new game
+// If it cannot answer, the state is created by the first tick it answers
+func (game *remoteGame) NewState() sdk.GameState {
+	state := &remoteState{game: game}
+	reply := sdk.HostState{}
+	if err := game.process.call("NewState", struct{}{}, &reply); err != nil {
+		game.process.logger.Warn("creating new state failed", "error", err)
+		return state
+	}
+	state.state, state.display = reply.State, reply.Display
+	return state
+}
+
+// ProcessState asks the process to run one tick of the state, skipping it if the process cannot
+// A tick that misses its deadline is skipped without killing the process, which runs every session of the game
+// Later ticks wait for it to finish rather than piling more calls onto the process
+func (game *remoteGame) ProcessState(state sdk.GameState, inputs sdk.InputData) {
+	remote := state.(*remoteState)
+	if remote.tick == nil {
+		remote.tickReply = sdk.HostState{}
+		remote.tick = game.process.goCall("ProcessState", &sdk.ProcessRequest{State: remote.state, Input: inputs}, &remote.tickReply)
+	}
+
+	deadline := time.NewTimer(game.process.settings.TickTimeout)
+	defer deadline.Stop()
+
+	select {
+	case <-remote.tick.done:
+	case <-deadline.C:
+		remote.setFailing(errGameProcessSlow)
+		return
+	}
+	err := remote.tick.err
+	remote.tick = nil
+	if err != nil {
+		remote.setFailing(err)
+		return
+	}
+	remote.setFailing(nil)
+	remote.state, remote.display = remote.tickReply.State, remote.tickReply.Display
+}
+
+// setFailing logs when the state's ticks start failing and when they recover
+func (state *remoteState) setFailing(err error) {
+	if err != nil && !state.failing {
+		state.game.process.logger.Warn("processing state failed, skipping ticks until it succeeds", "error", err)
+	} else if err == nil && state.failing {
+		state.game.process.logger.Info("processing state recovered")
+	}
+	state.failing = err != nil
+}
+
+// GameVersion returns the release the process reported, empty if it did not report one
+func (game *remoteGame) GameVersion() string {
+	return game.version
+}
+
+// StateCodec returns the codec the process encodes states with
+func (game *remoteGame) StateCodec() sdk.StateCodec {
+	return game.codec
+}
+
+// StateMigrations returns a migration from each older schema version, run by the process
+func (game *remoteGame) StateMigrations() []sdk.StateMigration {
+	migrations := make([]sdk.StateMigration, game.migrations)
+	for i := range migrations {
+		version := i + 1
+		migrations[i] = func(codec sdk.StateCodec, state []byte) ([]byte, error) {
+			migrated := []byte{}
+			err := game.process.call("MigrateState", &sdk.MigrateRequest{Version: version, State: state}, &migrated)
+			return migrated, err
+		}
+	}
+	return migrations
+}
+
+// RenderPreview asks the process to draw the display data
+func (game *previewingRemoteGame) RenderPreview(display sdk.DisplayData) (image.Image, error) {
+	encoded := []byte{}
+	if err := game.process.call("RenderPreview", display, &encoded); err != nil {
+		return nil, err
+	}
+	return png.Decode(bytes.NewReader(encoded))
+}
+
+// GetDisplayData returns what the state displayed after the last tick the process answered
+func (state *remoteState) GetDisplayData() sdk.DisplayData {
+	return state.display
+}
+
+// MarshalState returns the state, which is already encoded with the game's codec
+func (state *remoteState) MarshalState(codec sdk.StateCodec) ([]byte, error) {
+	if state.state == nil {
+		return nil, errNoRemoteState
+	}
+	return state.state, nil
+}
+
+// UnmarshalState has the process check the state can be loaded and what it displays
+func (state *remoteState) UnmarshalState(codec sdk.StateCodec, data []byte) error {
+	reply := sdk.HostState{}
+	if err := state.game.process.call("LoadState", data, &reply); err != nil {
+		return err
+	}
+	state.state, state.display = reply.State, reply.Display
+	return nil
+}
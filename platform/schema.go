@@ -0,0 +1,254 @@
+package platform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/game-sharing/service/sdk"
+	"github.com/gomodule/redigo/redis"
+)
+
+// stateMigrations holds the migrations of each game's saved states, the first upgrades schema version 1 to 2
+var stateMigrations = map[GameID][]sdk.StateMigration{}
+var stateMigrationsMux sync.RWMutex
+
+// stateBlobMagic starts saved states that have a header, older saved states are JSON and start with '{'
+const stateBlobMagic = "\x00GSS"
+
+// maxStateHeaderLength bounds the header read from a saved state
+const maxStateHeaderLength = 4096
+
+// StateHeader describes how a saved state or checkpoint was encoded
+type StateHeader struct {
+	GameID        GameID    `json:"gameID"`
+	GameVersion   string    `json:"gameVersion"`
+	SchemaVersion int       `json:"schemaVersion"`
+	Codec         string    `json:"codec"`
+	Compression   string    `json:"compression,omitempty"`
+	SavedOn       time.Time `json:"savedOn"`
+}
+
+// VersionedState is a saved state along with its header, the state is not compressed
+type VersionedState struct {
+	StateHeader
+	State []byte
+}
+
+// RegisterStateMigrations sets the migrations of a game's saved states in order, replacing any registered before
+// Schema version 1 is the format states were saved in before they were versioned, so a game with n migrations saves version n+1
+func RegisterStateMigrations(gameID GameID, migrations ...sdk.StateMigration) {
+	stateMigrationsMux.Lock()
+	defer stateMigrationsMux.Unlock()
+
+	stateMigrations[gameID] = migrations
+}
+
+// StateSchemaVersion returns the schema version a game's states are saved in
+func StateSchemaVersion(gameID GameID) int {
+	stateMigrationsMux.RLock()
+	defer stateMigrationsMux.RUnlock()
+
+	return len(stateMigrations[gameID]) + 1
+}
+
+// encodeState encodes a game state with the server's codec, adding a header with the current game and schema versions
+// The saved date is zero for checkpoints of live game sessions
+func encodeState(server GameServer, state sdk.GameState, savedDate time.Time) ([]byte, error) {
+	codec := server.GetStateCodec()
+	model, err := state.MarshalState(codec)
+	if err != nil {
+		return nil, err
+	}
+
+	gameID := server.GetGameID()
+	return encodeVersionedState(&VersionedState{
+		StateHeader: StateHeader{
+			GameID:        gameID,
+			GameVersion:   gameVersion(server.GetGame()),
+			SchemaVersion: StateSchemaVersion(gameID),
+			Codec:         codec.Name(),
+			Compression:   AppConfig.GameSettings(gameID).Compression,
+			SavedOn:       savedDate,
+		},
+		State: model,
+	})
+}
+
+// gameVersion returns the release a game reports, empty for games that do not
+func gameVersion(game sdk.Game) string {
+	if versioned, ok := game.(sdk.Versioned); ok {
+		return versioned.GameVersion()
+	}
+	return ""
+}
+
+// encodeVersionedState writes the magic, the length of the JSON header, the header, then the compressed state
+func encodeVersionedState(versioned *VersionedState) ([]byte, error) {
+	header, err := json.Marshal(&versioned.StateHeader)
+	if err != nil {
+		return nil, err
+	}
+	state, err := compressState(versioned.Compression, versioned.State)
+	if err != nil {
+		return nil, err
+	}
+
+	blob := append([]byte(stateBlobMagic), binary.AppendUvarint(nil, uint64(len(header)))...)
+	blob = append(blob, header...)
+	return append(blob, state...), nil
+}
+
+// decodeVersionedState returns a stored state with its header, and whether it was stored in an older format
+// States saved before they had a header are JSON, either bare at schema version 1 or in a JSON envelope
+func decodeVersionedState(gameID GameID, data []byte) (*VersionedState, bool, error) {
+	if !bytes.HasPrefix(data, []byte(stateBlobMagic)) {
+		versioned := &VersionedState{StateHeader: StateHeader{GameID: gameID, SchemaVersion: 1}, State: data}
+		envelope := &struct {
+			StateHeader
+			State json.RawMessage `json:"state"`
+		}{}
+		if err := json.Unmarshal(data, envelope); err == nil && envelope.SchemaVersion != 0 && envelope.State != nil {
+			versioned = &VersionedState{StateHeader: envelope.StateHeader, State: envelope.State}
+		}
+		versioned.Codec = sdk.JSONCodec.Name()
+
+		// Games saved the date along with their own state before the platform kept it
+		legacy := &struct {
+			SavedDate time.Time `json:"savedDate"`
+		}{}
+		if err := json.Unmarshal(versioned.State, legacy); err == nil {
+			versioned.SavedOn = legacy.SavedDate
+		}
+		return versioned, true, nil
+	}
+
+	data = data[len(stateBlobMagic):]
+	length, n := binary.Uvarint(data)
+	if n <= 0 || length > maxStateHeaderLength || uint64(len(data)-n) < length {
+		return nil, false, fmt.Errorf("%w: truncated header", ErrStateDecoding)
+	}
+	versioned := &VersionedState{}
+	if err := json.Unmarshal(data[n:n+int(length)], &versioned.StateHeader); err != nil {
+		return nil, false, fmt.Errorf("%w: header: %v", ErrStateDecoding, err)
+	}
+	state, err := decompressState(versioned.Compression, data[n+int(length):])
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %v", ErrStateDecoding, err)
+	}
+	versioned.State = state
+	return versioned, false, nil
+}
+
+// upgradeState migrates a stored state to the game's current schema version, returning it with the codec it is encoded with
+// It also returns whether the state differs from what was stored, including when it was stored in an older format
+func upgradeState(gameID GameID, data []byte) (*VersionedState, sdk.StateCodec, bool, error) {
+	versioned, changed, err := decodeVersionedState(gameID, data)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if versioned.GameID != gameID {
+		return nil, nil, false, fmt.Errorf("%w: state belongs to game %s", ErrStateDecoding, versioned.GameID)
+	}
+	codec, ok := sdk.LookupStateCodec(versioned.Codec)
+	if !ok {
+		return nil, nil, false, fmt.Errorf("%w: unknown codec %q", ErrStateDecoding, versioned.Codec)
+	}
+
+	stateMigrationsMux.RLock()
+	migrations := stateMigrations[gameID]
+	stateMigrationsMux.RUnlock()
+
+	// A state saved by a newer release cannot be read by this one
+	current := len(migrations) + 1
+	if versioned.SchemaVersion < 1 {
+		return nil, nil, false, fmt.Errorf("%w: schema version %d is not valid", ErrStateDecoding, versioned.SchemaVersion)
+	} else if versioned.SchemaVersion > current {
+		return nil, nil, false, fmt.Errorf("%w: schema version %d is newer than %d", ErrStateDecoding, versioned.SchemaVersion, current)
+	}
+
+	for versioned.SchemaVersion < current {
+		state, err := migrations[versioned.SchemaVersion-1](codec, versioned.State)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("%w: migrating from schema version %d: %v", ErrStateDecoding, versioned.SchemaVersion, err)
+		}
+		versioned.State = state
+		versioned.SchemaVersion++
+		changed = true
+	}
+	return versioned, codec, changed, nil
+}
+
+// StateMigrationReport counts what a batch migration of a game's saved states did
+type StateMigrationReport struct {
+	GameID   GameID
+	Scanned  int
+	Migrated int
+	Failed   int
+}
+
+// MigrateSavedStates upgrades every saved state of a game to its current schema version
+// States that cannot be migrated are logged and counted, nothing is written when dryRun is set
+func MigrateSavedStates(gameID GameID, dryRun bool) (StateMigrationReport, error) {
+	report := StateMigrationReport{GameID: gameID}
+	logger := Logger.With("game_id", gameID)
+
+	stateIDs, err := GetSavedStateIDs(gameID)
+	if err != nil {
+		return report, err
+	}
+	sort.Ints(stateIDs)
+
+	for _, stateID := range stateIDs {
+		report.Scanned++
+		migrated, err := migrateSavedState(gameID, stateID, dryRun)
+		if errors.Is(err, ErrStateDecoding) {
+			logger.Warn("migrating saved state failed", "state_id", stateID, "error", err)
+			report.Failed++
+			continue
+		} else if err != nil {
+			return report, err
+		}
+		if migrated {
+			report.Migrated++
+		}
+	}
+	return report, nil
+}
+
+// migrateSavedState rewrites a saved state at the game's current schema version, returning whether it needed to be
+func migrateSavedState(gameID GameID, stateID StateID, dryRun bool) (bool, error) {
+	for attempt := 0; attempt < maxStateWriteAttempts; attempt++ {
+		stored, err := LoadStateBlob(gameID, stateID)
+		if err == redis.ErrNil {
+			// Deleted since it was listed
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+
+		versioned, _, changed, err := upgradeState(gameID, stored)
+		if err != nil || !changed {
+			return false, err
+		}
+		if dryRun {
+			return true, nil
+		}
+
+		blob, err := encodeVersionedState(versioned)
+		if err != nil {
+			return false, fmt.Errorf("%w: %v", ErrStateEncoding, err)
+		}
+		replaced, err := ReplaceStateBlob(gameID, stateID, stored, blob)
+		if err != nil || replaced {
+			return replaced, err
+		}
+		waitToRetry(attempt)
+	}
+	return false, errStateWriteConflict
+}
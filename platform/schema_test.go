@@ -0,0 +1,142 @@
+package platform
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/game-sharing/service/games/newgame"
+	"github.com/game-sharing/service/sdk"
+)
+
+// renameMigration returns a migration renaming a field of a JSON state
+func renameMigration(from string, to string) sdk.StateMigration {
+	return func(codec sdk.StateCodec, state []byte) ([]byte, error) {
+		return bytes.Replace(state, []byte(`"`+from+`"`), []byte(`"`+to+`"`), 1), nil
+	}
+}
+
+// headerBlob encodes a JSON state with a header, failing the test if it cannot
+func headerBlob(t *testing.T, header StateHeader, state string) []byte {
+	blob, err := encodeVersionedState(&VersionedState{StateHeader: header, State: []byte(state)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blob
+}
+
+func TestUpgradeState(t *testing.T) {
+	RegisterStateMigrations("test", renameMigration("pos", "position"), renameMigration("position", "place"))
+	t.Cleanup(func() { RegisterStateMigrations("test") })
+
+	savedOn := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		data    []byte
+		state   string
+		savedOn time.Time
+		changed bool
+	}{
+		{"bare JSON", []byte(`{"pos":1,"savedDate":"2020-01-01T00:00:00Z"}`), `{"place":1,"savedDate":"2020-01-01T00:00:00Z"}`, savedOn, true},
+		{"JSON envelope", []byte(`{"gameID":"test","schemaVersion":2,"codec":"json","state":{"position":1}}`), `{"place":1}`, time.Time{}, true},
+		{"header at version 1", headerBlob(t, StateHeader{GameID: "test", SchemaVersion: 1, Codec: "json", SavedOn: savedOn}, `{"pos":1}`), `{"place":1}`, savedOn, true},
+		{"compressed header at version 2", headerBlob(t, StateHeader{GameID: "test", SchemaVersion: 2, Codec: "json", Compression: compressionGzip}, `{"position":1}`), `{"place":1}`, time.Time{}, true},
+		{"current header", headerBlob(t, StateHeader{GameID: "test", SchemaVersion: 3, Codec: "json", SavedOn: savedOn}, `{"place":1}`), `{"place":1}`, savedOn, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			versioned, codec, changed, err := upgradeState("test", test.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(versioned.State) != test.state {
+				t.Errorf("got state %s, want %s", versioned.State, test.state)
+			}
+			if versioned.SchemaVersion != 3 {
+				t.Errorf("got schema version %d, want 3", versioned.SchemaVersion)
+			}
+			if codec != sdk.JSONCodec {
+				t.Errorf("got codec %s, want json", codec.Name())
+			}
+			if !versioned.SavedOn.Equal(test.savedOn) {
+				t.Errorf("got saved date %v, want %v", versioned.SavedOn, test.savedOn)
+			}
+			if changed != test.changed {
+				t.Errorf("got changed %v, want %v", changed, test.changed)
+			}
+		})
+	}
+}
+
+func TestUpgradeStateRejects(t *testing.T) {
+	RegisterStateMigrations("test", renameMigration("pos", "position"))
+	t.Cleanup(func() { RegisterStateMigrations("test") })
+
+	truncated := headerBlob(t, StateHeader{GameID: "test", SchemaVersion: 1, Codec: "json"}, `{}`)[:len(stateBlobMagic)+3]
+
+	// The header claims the state is compressed but it is not
+	uncompressed := headerBlob(t, StateHeader{GameID: "test", SchemaVersion: 1, Codec: "json"}, `{}`)
+	uncompressed = bytes.Replace(uncompressed, []byte(`"codec":"json"`), []byte(`"codec":"json","compression":"gzip"`), 1)
+	uncompressed[len(stateBlobMagic)] += byte(len(`,"compression":"gzip"`))
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"schema version 0", headerBlob(t, StateHeader{GameID: "test", SchemaVersion: 0, Codec: "json"}, `{}`)},
+		{"negative schema version", headerBlob(t, StateHeader{GameID: "test", SchemaVersion: -1, Codec: "json"}, `{}`)},
+		{"negative schema version in an envelope", []byte(`{"gameID":"test","schemaVersion":-1,"state":{}}`)},
+		{"newer schema version", headerBlob(t, StateHeader{GameID: "test", SchemaVersion: 3, Codec: "json"}, `{}`)},
+		{"another game", headerBlob(t, StateHeader{GameID: "other", SchemaVersion: 1, Codec: "json"}, `{}`)},
+		{"unknown codec", headerBlob(t, StateHeader{GameID: "test", SchemaVersion: 1, Codec: "xml"}, `{}`)},
+		{"truncated header", truncated},
+		{"state not compressed", uncompressed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, _, err := upgradeState("test", test.data); !errors.Is(err, ErrStateDecoding) {
+				t.Errorf("got error %v, want ErrStateDecoding", err)
+			}
+		})
+	}
+}
+
+// unversionedGame hides the version a game reports
+type unversionedGame struct {
+	sdk.Game
+}
+
+func TestEncodeStateHeader(t *testing.T) {
+	useTestDatabase(t)
+	RegisterStateMigrations("0", renameMigration("pos", "position"))
+	t.Cleanup(func() { RegisterStateMigrations("0") })
+
+	tests := []struct {
+		name    string
+		game    sdk.Game
+		version string
+	}{
+		{"versioned game", &newgame.NewGameServer{}, "1"},
+		{"unversioned game", unversionedGame{&newgame.NewGameServer{}}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := NewServerLogic("0", 0, test.game)
+			savedOn := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+			blob, err := encodeState(server, server.NewState().State, savedOn)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			versioned, legacy, err := decodeVersionedState("0", blob)
+			if err != nil || legacy {
+				t.Fatalf("decoding the state: legacy %v, error %v", legacy, err)
+			}
+			want := StateHeader{GameID: "0", GameVersion: test.version, SchemaVersion: 2, Codec: sdk.BinaryCodec.Name(), SavedOn: savedOn}
+			if versioned.StateHeader != want {
+				t.Errorf("got header %+v, want %+v", versioned.StateHeader, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package platform
+
+import (
+	"context"
+	"net/http"
+)
+
+// namedServer is an HTTP server along with the name used in logs
+type namedServer struct {
+	name   string
+	server *http.Server
+}
+
+// newHTTPServer returns a server for the handler using the configured timeouts
+func newHTTPServer(addr string, handler http.Handler) *http.Server {
+	config := AppConfig.Server
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       config.ReadTimeout,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+	}
+}
+
+// newServers returns the servers to run, either one unified server or separate REST and WebSocket servers
+func newServers() []namedServer {
+	config := AppConfig.Server
+	restHandler := CORSMiddleware(MainRouter)
+	if config.Unified {
+		return []namedServer{{"HTTP and WebSocket", newHTTPServer(config.Addr, restHandler)}}
+	}
+	return []namedServer{
+		{"HTTP", newHTTPServer(config.Addr, restHandler)},
+		{"WebSocket", newHTTPServer(config.WSAddr, WSRouter)},
+	}
+}
+
+// startServers runs each server in its own goroutine, reporting failures on the returned channel
+func startServers(servers []namedServer) <-chan error {
+	config := AppConfig.Server
+	serverErrors := make(chan error, len(servers))
+	for _, named := range servers {
+		named := named
+		Logger.Info(named.name+" server started", "addr", named.server.Addr, "tls", config.TLSCert != "")
+		go func() {
+			var err error
+			if config.TLSCert != "" {
+				err = named.server.ListenAndServeTLS(config.TLSCert, config.TLSKey)
+			} else {
+				err = named.server.ListenAndServe()
+			}
+			if err != http.ErrServerClosed {
+				serverErrors <- err
+			}
+		}()
+	}
+	return serverErrors
+}
+
+// shutdownServers stops the servers from accepting connections and lets in-flight requests finish
+func shutdownServers(ctx context.Context, servers []namedServer) {
+	for _, named := range servers {
+		if err := named.server.Shutdown(ctx); err != nil {
+			Logger.Error(named.name+" server shutdown failed", "error", err)
+		}
+	}
+}
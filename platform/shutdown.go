@@ -0,0 +1,65 @@
+package platform
+
+import (
+	"context"
+	"strconv"
+)
+
+// ShutdownHubs ends every live game session and saves it for each of its players
+// Their checkpoints are kept so they can be restored as live sessions on startup
+// Hubs already being ended are waited for rather than saved twice or checkpointed again
+func ShutdownHubs(reason string) {
+	for _, hub := range LiveHubs() {
+		if !hub.ending.CompareAndSwap(false, true) {
+			continue
+		}
+		hub.Stop(reason)
+		hub.checkpoint()
+		saveForPlayers(hub)
+		RemoveHub(hub.server.GetGameID(), hub.id)
+	}
+	endingHubs.Wait()
+}
+
+// saveForPlayers saves a hub's state and adds it to each player's saved states
+// Errors are logged, the returned state is nil if the game could not be saved at all
+func saveForPlayers(hub *Hub) (*State, error) {
+	gameID := hub.server.GetGameID()
+
+	newStateID, snapshot, err := saveHub(hub)
+	if err != nil {
+		hub.logger.Error("saving live game session failed", "error", err)
+		return nil, err
+	}
+	newState := &State{
+		ID:      strconv.Itoa(newStateID),
+		SavedOn: snapshot.SavedOn,
+		Preview: captureStatePreview(hub.logger, gameID, newStateID, snapshot.Display),
+	}
+
+	var addErr error
+	for _, userID := range hub.Players() {
+		if err := AddToUserStates(gameID, userID, newState); err != nil {
+			hub.logger.Error("adding state to user's list failed", "user_id", userID, "error", err)
+			addErr = err
+		}
+	}
+	hub.logger.Info("live game session saved for its players", "saved_state_id", newStateID, "players", hub.Players())
+	return newState, addErr
+}
+
+// waitForConnections waits for the clients to finish sending their close messages
+func waitForConnections(ctx context.Context) bool {
+	done := make(chan struct{})
+	go func() {
+		activeConnections.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
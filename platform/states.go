@@ -0,0 +1,259 @@
+package platform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+// Limits on the annotations of a saved state
+const (
+	maxStateTitleLength       = 100
+	maxStateDescriptionLength = 1000
+	maxStateTags              = 20
+	maxStateTagLength         = 32
+)
+
+// Number of saved states listed in a page
+const (
+	defaultStatePageSize = 50
+	maxStatePageSize     = 200
+)
+
+// stateAnnotations is the body of a request to annotate a saved state, omitted fields are unchanged
+type stateAnnotations struct {
+	Title       *string   `json:"title"`
+	Description *string   `json:"description"`
+	Tags        *[]string `json:"tags"`
+	Favourite   *bool     `json:"favourite"`
+}
+
+// ListSavedStates returns a page of the caller's saved states for a game
+func ListSavedStates(w http.ResponseWriter, r *http.Request) {
+	gameID := mux.Vars(r)["gameID"]
+	if !gameExists(w, gameID) {
+		return
+	}
+
+	query, message := parseStateQuery(r)
+	if message != "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, message)
+		return
+	}
+
+	page, err := ListUserStates(gameID, authenticatedUser(r), query)
+	if errors.Is(err, ErrInvalidCursor) {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "The cursor must be the nextCursor of a previous page.")
+		return
+	} else if err != nil {
+		requestLogger(r).Error("reading saved states failed", "error", err)
+		writeError(w, http.StatusInternalServerError, CodeDatabaseError, "Database error encountered while reading saved states.")
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// parseStateQuery reads the listing options from the query string, returning a message describing the first invalid one
+func parseStateQuery(r *http.Request) (StateQuery, string) {
+	values := r.URL.Query()
+	query := StateQuery{
+		Sort:   SortBySavedOn,
+		Tag:    values.Get("tag"),
+		Limit:  defaultStatePageSize,
+		Cursor: values.Get("cursor"),
+	}
+
+	switch sort := values.Get("sort"); sort {
+	case "", SortBySavedOn:
+	case SortByName:
+		query.Sort = SortByName
+	default:
+		return query, "The sort must be savedOn or name."
+	}
+
+	// Newest first by default, names alphabetically
+	switch order := values.Get("order"); order {
+	case "":
+		query.Descending = query.Sort == SortBySavedOn
+	case "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return query, "The order must be asc or desc."
+	}
+
+	for _, bound := range []struct {
+		name  string
+		value *time.Time
+	}{{"from", &query.From}, {"to", &query.To}} {
+		if raw := values.Get(bound.name); raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return query, "The " + bound.name + " date must be in RFC 3339 format, e.g. 2006-01-02T15:04:05Z."
+			}
+			*bound.value = parsed
+		}
+	}
+
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxStatePageSize {
+			return query, fmt.Sprintf("The limit must be between 1 and %d.", maxStatePageSize)
+		}
+		query.Limit = limit
+	}
+
+	return query, ""
+}
+
+// GetSavedState returns one of the caller's saved states
+func GetSavedState(w http.ResponseWriter, r *http.Request) {
+	gameID, stateID, ok := savedStateParams(w, r)
+	if !ok {
+		return
+	}
+
+	state, err := GetUserState(gameID, authenticatedUser(r), stateID)
+	if err != nil {
+		writeUserStateError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, state)
+}
+
+// UpdateSavedState sets the title, description, tags or favourite of one of the caller's saved states
+func UpdateSavedState(w http.ResponseWriter, r *http.Request) {
+	gameID, stateID, ok := savedStateParams(w, r)
+	if !ok {
+		return
+	}
+
+	annotations := stateAnnotations{}
+	decoder := json.NewDecoder(io.LimitReader(r.Body, 64*1024))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&annotations); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "The request body must be a JSON object with title, description, tags or favourite.")
+		return
+	}
+	if message := annotations.validate(); message != "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, message)
+		return
+	}
+
+	state, err := UpdateUserState(gameID, authenticatedUser(r), stateID, annotations.apply)
+	if err != nil {
+		writeUserStateError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, state)
+}
+
+// DeleteSavedState removes one of the caller's saved states
+func DeleteSavedState(w http.ResponseWriter, r *http.Request) {
+	gameID, stateID, ok := savedStateParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := DeleteUserState(gameID, authenticatedUser(r), stateID); err != nil {
+		writeUserStateError(w, r, err)
+		return
+	}
+	stateOperationsCounter.WithLabelValues(gameID, "delete").Inc()
+	requestLogger(r).Info("saved state deleted")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// savedStateParams returns the game and saved state in the request's path
+func savedStateParams(w http.ResponseWriter, r *http.Request) (GameID, StateID, bool) {
+	params := mux.Vars(r)
+	gameID := params["gameID"]
+	if !gameExists(w, gameID) {
+		return "", 0, false
+	}
+
+	stateID := getValidStateID(w, r, params["stateID"])
+	if stateID == -1 {
+		return "", 0, false
+	}
+	annotateRequest(r, "game_id", gameID, "state_id", stateID)
+
+	return gameID, stateID, true
+}
+
+// writeUserStateError replies with the status and code matching an error from the user's saved states
+func writeUserStateError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, ErrStateNotFound) {
+		writeError(w, http.StatusNotFound, CodeStateNotFound, "State ID is not in the user's saved states.")
+		return
+	}
+
+	requestLogger(r).Error("updating saved states failed", "error", err)
+	writeError(w, http.StatusInternalServerError, CodeDatabaseError, "Database error encountered while updating saved states.")
+}
+
+// validate tidies the annotations, returning a message describing the first invalid one
+func (annotations *stateAnnotations) validate() string {
+	if annotations.Title != nil {
+		*annotations.Title = strings.TrimSpace(*annotations.Title)
+		if utf8.RuneCountInString(*annotations.Title) > maxStateTitleLength {
+			return fmt.Sprintf("The title must be at most %d characters.", maxStateTitleLength)
+		}
+		if strings.IndexFunc(*annotations.Title, unicode.IsControl) >= 0 {
+			return "The title must not contain control characters."
+		}
+	}
+
+	if annotations.Description != nil && utf8.RuneCountInString(*annotations.Description) > maxStateDescriptionLength {
+		return fmt.Sprintf("The description must be at most %d characters.", maxStateDescriptionLength)
+	}
+
+	if annotations.Tags != nil {
+		tags := []string{}
+		seen := make(map[string]bool)
+		for _, tag := range *annotations.Tags {
+			tag = strings.TrimSpace(tag)
+			if tag == "" || utf8.RuneCountInString(tag) > maxStateTagLength {
+				return fmt.Sprintf("Tags must be between 1 and %d characters.", maxStateTagLength)
+			}
+			if strings.IndexFunc(tag, unicode.IsControl) >= 0 {
+				return "Tags must not contain control characters."
+			}
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+		if len(tags) > maxStateTags {
+			return fmt.Sprintf("A saved state can have at most %d tags.", maxStateTags)
+		}
+		*annotations.Tags = tags
+	}
+
+	return ""
+}
+
+// apply sets the annotations that were given on the state
+func (annotations *stateAnnotations) apply(state *State) {
+	if annotations.Title != nil {
+		state.Title = *annotations.Title
+	}
+	if annotations.Description != nil {
+		state.Description = *annotations.Description
+	}
+	if annotations.Tags != nil {
+		state.Tags = *annotations.Tags
+	}
+	if annotations.Favourite != nil {
+		state.Favourite = *annotations.Favourite
+	}
+}
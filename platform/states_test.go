@@ -0,0 +1,134 @@
+package platform
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// addListedStates adds twelve saved states to alice's list, one an hour
+// Titles are in a different order than the states were saved, and even states are tagged
+func addListedStates(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 12; i++ {
+		state := &State{ID: strconv.Itoa(i), SavedOn: base.Add(time.Duration(i) * time.Hour)}
+
+		// Every third title is capitalized, which does not change their order
+		state.Title = fmt.Sprintf("%c title", 'a'+(i*5)%12)
+		if i%3 == 0 {
+			state.Title = strings.ToUpper(state.Title)
+		}
+		if i%2 == 0 {
+			state.Tags = []string{"even"}
+		}
+		if err := AddToUserStates("0", "alice", state); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// listAllStates follows the cursors of a listing, returning the IDs of every page
+func listAllStates(t *testing.T, api *testAPI, token string, query string, limit int) [][]string {
+	pages := [][]string{}
+	cursor := ""
+	for {
+		path := "/v1/games/0/states?limit=" + strconv.Itoa(limit) + "&" + query
+		if cursor != "" {
+			path += "&cursor=" + url.QueryEscape(cursor)
+		}
+		status, body := api.do("GET", path, token, "")
+		if status != http.StatusOK {
+			t.Fatalf("got status %d, body %v", status, body)
+		}
+
+		page := []string{}
+		for _, state := range body["states"].([]interface{}) {
+			page = append(page, state.(map[string]interface{})["id"].(string))
+		}
+		pages = append(pages, page)
+
+		next, _ := body["nextCursor"].(string)
+		if next == "" {
+			return pages
+		}
+		if len(pages) > 20 {
+			t.Fatal("the listing does not end")
+		}
+		cursor = next
+	}
+}
+
+func TestListSavedStates(t *testing.T) {
+	api := newTestAPI(t)
+	alice := api.login("alice")
+	addListedStates(t)
+
+	byName := []string{"0", "5", "10", "3", "8", "1", "6", "11", "4", "9", "2", "7"}
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"newest first", "", []string{"11", "10", "9", "8", "7", "6", "5", "4", "3", "2", "1", "0"}},
+		{"oldest first", "order=asc", []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11"}},
+		{"by name", "sort=name", byName},
+		{"by name descending", "sort=name&order=desc", []string{"7", "2", "9", "4", "11", "6", "1", "8", "3", "10", "5", "0"}},
+		{"tag", "tag=even", []string{"10", "8", "6", "4", "2", "0"}},
+		{"tag by name", "tag=even&sort=name", []string{"0", "10", "8", "6", "4", "2"}},
+		{"unknown tag", "tag=odd", []string{}},
+		{"date range", "from=2026-01-01T03:00:00Z&to=2026-01-01T06:00:00Z", []string{"6", "5", "4", "3"}},
+		{"date range by name", "sort=name&from=2026-01-01T03:00:00Z&to=2026-01-01T08:00:00Z", []string{"5", "3", "8", "6", "4", "7"}},
+		{"from only", "from=2026-01-01T10:00:00Z&order=asc", []string{"10", "11"}},
+		{"tag and date range", "tag=even&from=2026-01-01T03:00:00Z&to=2026-01-01T09:00:00Z", []string{"8", "6", "4"}},
+	}
+	for _, test := range tests {
+		for _, limit := range []int{1, 5, 50} {
+			t.Run(fmt.Sprintf("%s/limit %d", test.name, limit), func(t *testing.T) {
+				pages := listAllStates(t, api, alice, test.query, limit)
+				got := []string{}
+				for i, page := range pages {
+					if len(page) > limit || (len(page) < limit && i < len(pages)-1) {
+						t.Errorf("page %d has %d states with a limit of %d", i, len(page), limit)
+					}
+					got = append(got, page...)
+				}
+				if !reflect.DeepEqual(got, test.want) {
+					t.Errorf("got %v, want %v", got, test.want)
+				}
+			})
+		}
+	}
+}
+
+func TestListSavedStatesInvalidQuery(t *testing.T) {
+	api := newTestAPI(t)
+	alice := api.login("alice")
+	addListedStates(t)
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"malformed cursor", "cursor=!!"},
+		{"cursor not issued by a listing", "cursor=" + url.QueryEscape("not a cursor")},
+		{"zero limit", "limit=0"},
+		{"limit too large", "limit=" + strconv.Itoa(maxStatePageSize+1)},
+		{"limit not a number", "limit=ten"},
+		{"unknown sort", "sort=size"},
+		{"unknown order", "order=up"},
+		{"malformed date", "from=yesterday"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, body := api.do("GET", "/v1/games/0/states?"+test.query, alice, "")
+			if status != http.StatusBadRequest || errorCode(body) != CodeInvalidRequest {
+				t.Errorf("got status %d and code %q, want %d and %q", status, errorCode(body), http.StatusBadRequest, CodeInvalidRequest)
+			}
+		})
+	}
+}
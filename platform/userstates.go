@@ -0,0 +1,593 @@
+package platform
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// State is the model for state information
+// The title, description, tags and favourite are set by each user for their own list
+type State struct {
+	ID          string    `json:"id"`
+	SavedOn     time.Time `json:"savedOn"`
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	Favourite   bool      `json:"favourite,omitempty"`
+
+	// Set when a preview of the display was captured as the state was saved
+	Preview bool `json:"preview,omitempty"`
+}
+
+// Orders a user's saved states can be listed in
+const (
+	SortBySavedOn = "savedOn"
+	SortByName    = "name"
+)
+
+// StateQuery selects a page of a user's saved states
+type StateQuery struct {
+	// SortBySavedOn or SortByName
+	Sort       string
+	Descending bool
+
+	// Only list states with the tag, when set
+	Tag string
+
+	// Only list states saved within the range, when set
+	From time.Time
+	To   time.Time
+
+	// Maximum number of states in the page
+	Limit int
+
+	// Where the page starts, from the NextCursor of the previous page
+	Cursor string
+}
+
+// StatePage is a page of a user's saved states
+type StatePage struct {
+	States     []State `json:"states"`
+	NextCursor string  `json:"nextCursor,omitempty"`
+}
+
+// ErrInvalidCursor is returned when a page cursor was not issued by ListUserStates
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+// Every index is a sorted set with equal scores, so its members are ordered by their bytes
+// Members end with the zero-padded state ID, which keeps them unique and breaks ties
+func getUserStateEntriesKey(gameID GameID, userID UserID) string {
+	return "table: UserStateEntries, gameID: " + gameID + ", userID: " + userID
+}
+
+func getUserStateIndexKey(gameID GameID, userID UserID, tag string, sort string) string {
+	key := "table: UserStateIndex, gameID: " + gameID + ", userID: " + userID
+	if tag != "" {
+		key += ", tag: " + tag
+	}
+	return key + ", sort: " + sort
+}
+
+func getUserStatesObjectPrefix(gameID GameID, userID UserID) string {
+	return "table: UserStates, gameID: " + gameID + ", userID: " + userID
+}
+
+func getStateOwnersObjectPrefix(gameID GameID, stateID StateID) string {
+	return "table: StateOwners, gameID: " + gameID + ", stateID: " + strconv.Itoa(stateID)
+}
+
+// savedOnPrefix returns the start of the savedOn index members of states saved in the same millisecond
+func savedOnPrefix(savedOn time.Time) string {
+	millis := int64(0)
+	if !savedOn.IsZero() && savedOn.UnixMilli() > 0 {
+		millis = savedOn.UnixMilli()
+	}
+	return fmt.Sprintf("%015d:", millis)
+}
+
+// stateIndexMembers returns the member of each index the state is listed in
+func stateIndexMembers(gameID GameID, userID UserID, state *State) map[string]string {
+	stateID, _ := strconv.Atoi(state.ID)
+	paddedID := fmt.Sprintf("%012d", stateID)
+	bySavedOn := savedOnPrefix(state.SavedOn) + paddedID
+	byName := strings.ToLower(state.Title) + "\x00" + paddedID
+
+	members := map[string]string{
+		getUserStateIndexKey(gameID, userID, "", SortBySavedOn): bySavedOn,
+		getUserStateIndexKey(gameID, userID, "", SortByName):    byName,
+	}
+	for _, tag := range state.Tags {
+		members[getUserStateIndexKey(gameID, userID, tag, SortBySavedOn)] = bySavedOn
+		members[getUserStateIndexKey(gameID, userID, tag, SortByName)] = byName
+	}
+	return members
+}
+
+// memberStateID returns the state ID an index member refers to
+func memberStateID(member string) string {
+	stateID, _ := strconv.Atoi(member[strings.LastIndexAny(member, ":\x00")+1:])
+	return strconv.Itoa(stateID)
+}
+
+// maxStateWriteAttempts is how many times a saved state is read and written again when other clients keep changing it first
+const maxStateWriteAttempts = 10
+
+// errStateWriteConflict is returned when a saved state changed during every attempt to write it
+var errStateWriteConflict = errors.New("saved state changed concurrently on every attempt")
+
+// waitToRetry sleeps for a random time that grows with each attempt, so clients writing the same state stop colliding
+func waitToRetry(attempt int) {
+	time.Sleep(time.Duration(rand.Int63n(int64(time.Millisecond) << attempt)))
+}
+
+// replaceStateScript replaces a state model and its index members if it is still stored as expected
+// KEYS are the entries, then the index of each member to remove, then the index of each member to add
+// ARGV are the state ID, the expected and replacement JSON, empty when absent, the number of members to remove, then the members
+// It returns 0 without changing anything if the state is not stored as expected
+var replaceStateScript = redis.NewScript(-1, `
+local current = redis.call("HGET", KEYS[1], ARGV[1]) or ""
+if current ~= ARGV[2] then
+	return 0
+end
+local removed = tonumber(ARGV[4])
+for i = 2, #KEYS do
+	if i <= removed + 1 then
+		redis.call("ZREM", KEYS[i], ARGV[i + 3])
+	else
+		redis.call("ZADD", KEYS[i], 0, ARGV[i + 3])
+	end
+end
+if ARGV[3] == "" then
+	redis.call("HDEL", KEYS[1], ARGV[1])
+else
+	redis.call("HSET", KEYS[1], ARGV[1], ARGV[3])
+end
+return 1
+`)
+
+// storedState is a state model with the JSON it is stored as
+type storedState struct {
+	*State
+	json []byte
+}
+
+// replaceStateArgs returns the keys and arguments of replaceStateScript
+// A nil old state is only added, and a nil replacement only removes the old state
+func replaceStateArgs(gameID GameID, userID UserID, stateID string, old *storedState, replacement *State) ([]interface{}, error) {
+	keys := []interface{}{getUserStateEntriesKey(gameID, userID)}
+	args := []interface{}{stateID, "", "", 0}
+	if old != nil {
+		args[1] = old.json
+		removed := stateIndexMembers(gameID, userID, old.State)
+		for key, member := range removed {
+			keys = append(keys, key)
+			args = append(args, member)
+		}
+		args[3] = len(removed)
+	}
+	if replacement != nil {
+		jsonValue, encodeErr := json.Marshal(replacement)
+		if encodeErr != nil {
+			return nil, encodeErr
+		}
+		args[2] = jsonValue
+		for key, member := range stateIndexMembers(gameID, userID, replacement) {
+			keys = append(keys, key)
+			args = append(args, member)
+		}
+	}
+
+	// Scripts with a variable number of keys are given the count first
+	return append(append([]interface{}{len(keys)}, keys...), args...), nil
+}
+
+// replaceUserState replaces a state model and its listings if it has not changed since it was read
+// It returns false if another client changed the state first
+func replaceUserState(conn redis.Conn, gameID GameID, userID UserID, stateID string, old *storedState, replacement *State) (bool, error) {
+	args, err := replaceStateArgs(gameID, userID, stateID, old, replacement)
+	if err != nil {
+		return false, err
+	}
+
+	start := time.Now()
+	replaced, err := redis.Bool(replaceStateScript.Do(conn, args...))
+	redisDurationHistogram.WithLabelValues("EVALSHA").Observe(time.Since(start).Seconds())
+
+	return replaced, err
+}
+
+// readUserState returns one of a user's state models, or ErrStateNotFound
+func readUserState(conn redis.Conn, gameID GameID, userID UserID, stateID string) (*storedState, error) {
+	storedValue, readErr := redis.Bytes(doCommand(conn, "HGET", getUserStateEntriesKey(gameID, userID), stateID))
+	if readErr == redis.ErrNil {
+		return nil, ErrStateNotFound
+	} else if readErr != nil {
+		return nil, readErr
+	}
+
+	state := &State{}
+	if decodeErr := json.Unmarshal(storedValue, state); decodeErr != nil {
+		return nil, decodeErr
+	}
+	return &storedState{state, storedValue}, nil
+}
+
+// migrateUserStates moves a user's legacy JSON list of states into the entries and indexes
+// States already in the entries are kept, so a list written again by an old instance does not undo later changes
+func migrateUserStates(conn redis.Conn, gameID GameID, userID UserID) error {
+	legacyKey := getUserStatesObjectPrefix(gameID, userID)
+
+	// Most users have been migrated already, so only watch the list if there is one
+	exists, err := redis.Bool(doCommand(conn, "EXISTS", legacyKey))
+	if err != nil || !exists {
+		return err
+	}
+
+	for attempt := 0; attempt < maxStateWriteAttempts; attempt++ {
+		if attempt > 0 {
+			waitToRetry(attempt)
+		}
+
+		// Old instances may still be appending to the list
+		if _, err := doCommand(conn, "WATCH", legacyKey); err != nil {
+			return err
+		}
+
+		storedValue, readErr := redis.Bytes(doCommand(conn, "GET", legacyKey))
+		if readErr == redis.ErrNil {
+			conn.Do("UNWATCH")
+			return nil
+		} else if readErr != nil {
+			conn.Do("UNWATCH")
+			return readErr
+		}
+
+		stateList := []State{}
+		if decodeErr := json.Unmarshal(storedValue, &stateList); decodeErr != nil {
+			conn.Do("UNWATCH")
+			return decodeErr
+		}
+
+		conn.Send("MULTI")
+		for i := range stateList {
+			args, err := replaceStateArgs(gameID, userID, stateList[i].ID, nil, &stateList[i])
+			if err != nil {
+				conn.Do("DISCARD")
+				return err
+			}
+			replaceStateScript.Send(conn, args...)
+			if stateID, err := strconv.Atoi(stateList[i].ID); err == nil {
+				conn.Send("SADD", getStateOwnersObjectPrefix(gameID, stateID), userID)
+			}
+		}
+		conn.Send("DEL", legacyKey)
+		reply, err := doCommand(conn, "EXEC")
+		if err != nil || reply != nil {
+			return err
+		}
+	}
+	return errStateWriteConflict
+}
+
+// MigrateAllUserStates moves every legacy JSON list of states into the entries and indexes, returning how many were moved
+func MigrateAllUserStates() (int, error) {
+	conn := DatabasePool.Get()
+	defer conn.Close()
+
+	const legacyPrefix = "table: UserStates, gameID: "
+	migrated := 0
+	cursor := "0"
+	for {
+		reply, err := redis.Values(doCommand(conn, "SCAN", cursor, "MATCH", legacyPrefix+"*", "COUNT", 100))
+		if err != nil {
+			return migrated, err
+		}
+		keys, err := redis.Strings(reply[1], nil)
+		if err != nil {
+			return migrated, err
+		}
+
+		for _, key := range keys {
+			gameID, userID, ok := strings.Cut(strings.TrimPrefix(key, legacyPrefix), ", userID: ")
+			if !ok {
+				continue
+			}
+			if err := migrateUserStates(conn, gameID, userID); err != nil {
+				return migrated, fmt.Errorf("migrating %q: %w", key, err)
+			}
+			migrated++
+		}
+
+		cursor, err = redis.String(reply[0], nil)
+		if err != nil || cursor == "0" {
+			return migrated, err
+		}
+	}
+}
+
+// AddToUserStates adds a state model to a user's list of saved states
+func AddToUserStates(gameID GameID, userID UserID, newState *State) error {
+	conn := DatabasePool.Get()
+	defer conn.Close()
+
+	if err := migrateUserStates(conn, gameID, userID); err != nil {
+		return err
+	}
+
+	// Record that the user has the state first, so it is never deleted while listed
+	if stateID, err := strconv.Atoi(newState.ID); err == nil {
+		if _, err := doCommand(conn, "SADD", getStateOwnersObjectPrefix(gameID, stateID), userID); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; attempt < maxStateWriteAttempts; attempt++ {
+		if attempt > 0 {
+			waitToRetry(attempt)
+		}
+
+		// Replace the state if the user already has it
+		old, err := readUserState(conn, gameID, userID, newState.ID)
+		if err != nil && err != ErrStateNotFound {
+			return err
+		}
+
+		replaced, err := replaceUserState(conn, gameID, userID, newState.ID, old, newState)
+		if err != nil || replaced {
+			return err
+		}
+	}
+	return errStateWriteConflict
+}
+
+// GetUserState returns one of the states in a user's list of saved states
+func GetUserState(gameID GameID, userID UserID, stateID StateID) (*State, error) {
+	conn := DatabasePool.Get()
+	defer conn.Close()
+
+	if err := migrateUserStates(conn, gameID, userID); err != nil {
+		return nil, err
+	}
+
+	stored, err := readUserState(conn, gameID, userID, strconv.Itoa(stateID))
+	if err != nil {
+		return nil, err
+	}
+	return stored.State, nil
+}
+
+// UpdateUserState changes one of the states in a user's list of saved states
+// The update is applied again if another client changed the state first
+func UpdateUserState(gameID GameID, userID UserID, stateID StateID, update func(*State)) (*State, error) {
+	conn := DatabasePool.Get()
+	defer conn.Close()
+
+	if err := migrateUserStates(conn, gameID, userID); err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; attempt < maxStateWriteAttempts; attempt++ {
+		if attempt > 0 {
+			waitToRetry(attempt)
+		}
+
+		old, err := readUserState(conn, gameID, userID, strconv.Itoa(stateID))
+		if err != nil {
+			return nil, err
+		}
+
+		updated := *old.State
+		update(&updated)
+		updated.ID = old.ID
+		replaced, err := replaceUserState(conn, gameID, userID, old.ID, old, &updated)
+		if err != nil {
+			return nil, err
+		} else if replaced {
+			return &updated, nil
+		}
+	}
+	return nil, errStateWriteConflict
+}
+
+// deleteStateIfUnownedScript removes a user from a state's owners, deleting the state and its preview once it has none
+var deleteStateIfUnownedScript = redis.NewScript(3, `
+redis.call("SREM", KEYS[1], ARGV[1])
+if redis.call("SCARD", KEYS[1]) == 0 then
+	return redis.call("DEL", KEYS[2], KEYS[3])
+end
+return 0
+`)
+
+// DeleteUserState removes a state from a user's list of saved states
+// The encoded game state and its preview are deleted too once no other user has it in their list
+func DeleteUserState(gameID GameID, userID UserID, stateID StateID) error {
+	conn := DatabasePool.Get()
+	defer conn.Close()
+
+	if err := migrateUserStates(conn, gameID, userID); err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt == maxStateWriteAttempts {
+			return errStateWriteConflict
+		} else if attempt > 0 {
+			waitToRetry(attempt)
+		}
+
+		old, err := readUserState(conn, gameID, userID, strconv.Itoa(stateID))
+		if err != nil {
+			return err
+		}
+
+		replaced, err := replaceUserState(conn, gameID, userID, old.ID, old, nil)
+		if err != nil {
+			return err
+		} else if replaced {
+			break
+		}
+	}
+
+	start := time.Now()
+	_, err := deleteStateIfUnownedScript.Do(conn, getStateOwnersObjectPrefix(gameID, stateID), getSavedStatesObjectPrefix(gameID, stateID), getStatePreviewsObjectPrefix(gameID, stateID), userID)
+	redisDurationHistogram.WithLabelValues("EVALSHA").Observe(time.Since(start).Seconds())
+
+	return err
+}
+
+// ListAllUserStates returns every state in a user's list of saved states, oldest first
+func ListAllUserStates(gameID GameID, userID UserID) ([]State, error) {
+	conn := DatabasePool.Get()
+	defer conn.Close()
+
+	if err := migrateUserStates(conn, gameID, userID); err != nil {
+		return nil, err
+	}
+
+	members, err := redis.Strings(doCommand(conn, "ZRANGEBYLEX", getUserStateIndexKey(gameID, userID, "", SortBySavedOn), "-", "+"))
+	if err != nil {
+		return nil, err
+	}
+
+	states, err := readIndexedStates(conn, gameID, userID, members)
+	if err != nil {
+		return nil, err
+	}
+
+	stateList := []State{}
+	for _, state := range states {
+		if state != nil {
+			stateList = append(stateList, *state)
+		}
+	}
+	return stateList, nil
+}
+
+// ListUserStates returns a page of a user's list of saved states
+func ListUserStates(gameID GameID, userID UserID, query StateQuery) (*StatePage, error) {
+	conn := DatabasePool.Get()
+	defer conn.Close()
+
+	if err := migrateUserStates(conn, gameID, userID); err != nil {
+		return nil, err
+	}
+
+	// Narrow the range of the savedOn index to the dates, other orders are filtered while scanning
+	key := getUserStateIndexKey(gameID, userID, query.Tag, query.Sort)
+	lower, upper := "-", "+"
+	batch := query.Limit + 1
+	if query.Sort == SortBySavedOn {
+		if !query.From.IsZero() {
+			lower = "[" + savedOnPrefix(query.From)
+		}
+		if !query.To.IsZero() {
+			upper = "(" + savedOnPrefix(query.To.Add(time.Millisecond))
+		}
+	} else if (!query.From.IsZero() || !query.To.IsZero()) && batch < 100 {
+		batch = 100
+	}
+
+	// Continue after the last state of the previous page
+	if query.Cursor != "" {
+		after, err := base64.RawURLEncoding.DecodeString(query.Cursor)
+		if err != nil || len(after) == 0 {
+			return nil, ErrInvalidCursor
+		}
+		if query.Descending {
+			upper = "(" + string(after)
+		} else {
+			lower = "(" + string(after)
+		}
+	}
+
+	page := &StatePage{States: []State{}}
+	lastMember := ""
+	for {
+		var members []string
+		var err error
+		if query.Descending {
+			members, err = redis.Strings(doCommand(conn, "ZREVRANGEBYLEX", key, upper, lower, "LIMIT", 0, batch))
+		} else {
+			members, err = redis.Strings(doCommand(conn, "ZRANGEBYLEX", key, lower, upper, "LIMIT", 0, batch))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		states, err := readIndexedStates(conn, gameID, userID, members)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, state := range states {
+			if state == nil || !query.matches(state) {
+				continue
+			}
+			if len(page.States) == query.Limit {
+				page.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(lastMember))
+				return page, nil
+			}
+			page.States = append(page.States, *state)
+			lastMember = members[i]
+		}
+
+		if len(members) < batch {
+			return page, nil
+		}
+
+		// Scan the next batch after the last member read
+		if query.Descending {
+			upper = "(" + members[len(members)-1]
+		} else {
+			lower = "(" + members[len(members)-1]
+		}
+	}
+}
+
+// matches checks if a state was saved within the query's dates
+func (query *StateQuery) matches(state *State) bool {
+	if !query.From.IsZero() && state.SavedOn.Before(query.From) {
+		return false
+	}
+	if !query.To.IsZero() && state.SavedOn.After(query.To) {
+		return false
+	}
+	return true
+}
+
+// readIndexedStates returns the state models that index members refer to, nil for any that are missing
+func readIndexedStates(conn redis.Conn, gameID GameID, userID UserID, members []string) ([]*State, error) {
+	if len(members) == 0 {
+		return nil, nil
+	}
+
+	args := []interface{}{getUserStateEntriesKey(gameID, userID)}
+	for _, member := range members {
+		args = append(args, memberStateID(member))
+	}
+	storedValues, readErr := redis.ByteSlices(doCommand(conn, "HMGET", args...))
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	states := make([]*State, len(storedValues))
+	for i, storedValue := range storedValues {
+		if storedValue == nil {
+			continue
+		}
+		state := &State{}
+		if decodeErr := json.Unmarshal(storedValue, state); decodeErr != nil {
+			return nil, decodeErr
+		}
+		states[i] = state
+	}
+	return states, nil
+}
@@ -0,0 +1,128 @@
+package platform
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+// useTestDatabase points the database pool at an in-memory Redis for the test
+func useTestDatabase(t *testing.T) *miniredis.Miniredis {
+	server := miniredis.RunT(t)
+	AppConfig = DefaultConfig()
+	DatabasePool = &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", server.Addr())
+		},
+	}
+	t.Cleanup(func() {
+		DatabasePool.Close()
+	})
+	return server
+}
+
+// stateIDs returns the sorted IDs of the states
+func stateIDs(states []State) []string {
+	ids := []string{}
+	for _, state := range states {
+		ids = append(ids, state.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestAddToUserStatesConcurrently(t *testing.T) {
+	useTestDatabase(t)
+
+	const adds = 50
+	var wg sync.WaitGroup
+	for i := 0; i < adds; i++ {
+		wg.Add(1)
+		go func(stateID int) {
+			defer wg.Done()
+			if err := AddToUserStates("0", "alice", &State{ID: strconv.Itoa(stateID), SavedOn: time.Now()}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	states, err := ListAllUserStates("0", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(states) != adds {
+		t.Fatalf("got %d states, want %d", len(states), adds)
+	}
+	seen := map[string]bool{}
+	for _, state := range states {
+		seen[state.ID] = true
+	}
+	for i := 0; i < adds; i++ {
+		if !seen[strconv.Itoa(i)] {
+			t.Errorf("state %d is missing", i)
+		}
+	}
+}
+
+func TestMigrateLegacyUserStates(t *testing.T) {
+	server := useTestDatabase(t)
+
+	legacyKey := getUserStatesObjectPrefix("0", "alice")
+	server.Set(legacyKey, `[{"id":"1","savedOn":"2020-01-01T00:00:00Z","title":"first"},{"id":"2","savedOn":"2020-01-02T00:00:00Z"}]`)
+
+	// Adding a state migrates the legacy list first
+	if err := AddToUserStates("0", "alice", &State{ID: "3", SavedOn: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if server.Exists(legacyKey) {
+		t.Fatal("legacy list was not removed")
+	}
+	states, err := ListAllUserStates("0", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := stateIDs(states); len(ids) != 3 || ids[0] != "1" || ids[1] != "2" || ids[2] != "3" {
+		t.Fatalf("got states %v, want [1 2 3]", ids)
+	}
+	state, err := GetUserState("0", "alice", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Title != "first" {
+		t.Errorf("got title %q, want %q", state.Title, "first")
+	}
+
+	// A list written again by an old instance does not undo later changes
+	if _, err := UpdateUserState("0", "alice", 1, func(state *State) { state.Title = "renamed" }); err != nil {
+		t.Fatal(err)
+	}
+	server.Set(legacyKey, `[{"id":"1","savedOn":"2020-01-01T00:00:00Z","title":"first"},{"id":"4","savedOn":"2020-01-04T00:00:00Z"}]`)
+
+	migrated, err := MigrateAllUserStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated != 1 {
+		t.Errorf("migrated %d lists, want 1", migrated)
+	}
+	states, err = ListAllUserStates("0", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := stateIDs(states); len(ids) != 4 {
+		t.Fatalf("got states %v, want [1 2 3 4]", ids)
+	}
+	state, err = GetUserState("0", "alice", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Title != "renamed" {
+		t.Errorf("got title %q, want %q", state.Title, "renamed")
+	}
+}
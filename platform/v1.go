@@ -0,0 +1,259 @@
+package platform
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// User is the model for a user of the versioned API
+type User struct {
+	ID UserID `json:"id"`
+}
+
+// userRequest is the body of a request for a bearer token
+type userRequest struct {
+	ID     UserID `json:"id"`
+	Secret string `json:"secret"`
+}
+
+// Token is a bearer token issued to a user
+type Token struct {
+	UserID    UserID    `json:"userID"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Session is the model for a live game session
+type Session struct {
+	ID      string   `json:"id"`
+	GameID  GameID   `json:"gameID"`
+	Owner   UserID   `json:"owner"`
+	Players []UserID `json:"players"`
+	Invited []UserID `json:"invited"`
+}
+
+// sessionRequest is the body of a request to start a live game session
+type sessionRequest struct {
+	// The caller's saved state to load, a new game is started when empty
+	StateID string `json:"stateID"`
+}
+
+// RegisterV1Routes adds the versioned API to the router under /v1
+func RegisterV1Routes(router *mux.Router) {
+	v1 := router.PathPrefix("/v1").Subrouter()
+	v1.HandleFunc("/games", GetGames).Methods("GET")
+	v1.HandleFunc("/games/{gameID}/thumbnail", GetGameThumbnail).Methods("GET")
+	v1.HandleFunc("/users", CreateUser).Methods("POST")
+
+	admin := v1.PathPrefix("/admin").Subrouter()
+	admin.Use(RequireAdmin)
+	admin.HandleFunc("/games", ListCatalogue).Methods("GET")
+	admin.HandleFunc("/games", CreateGame).Methods("POST")
+	admin.HandleFunc("/games/{gameID}", GetCatalogueGame).Methods("GET")
+	admin.HandleFunc("/games/{gameID}", UpdateGame).Methods("PATCH")
+	admin.HandleFunc("/games/{gameID}", DeleteGame).Methods("DELETE")
+	admin.HandleFunc("/games/{gameID}/thumbnail", UploadGameThumbnail).Methods("PUT")
+	admin.HandleFunc("/hubs", ListHubs).Methods("GET")
+	admin.HandleFunc("/games/{gameID}/hubs/{hubID}", GetHubInfo).Methods("GET")
+	admin.HandleFunc("/games/{gameID}/hubs/{hubID}", TerminateHub).Methods("DELETE")
+	admin.HandleFunc("/games/{gameID}/hubs/{hubID}/saves", SaveHub).Methods("POST")
+	admin.HandleFunc("/games/{gameID}/hubs/{hubID}/pause", PauseHub).Methods("POST")
+	admin.HandleFunc("/games/{gameID}/hubs/{hubID}/resume", ResumeHub).Methods("POST")
+	admin.HandleFunc("/games/{gameID}/hubs/{hubID}/clients/{userID}", KickClient).Methods("DELETE")
+	admin.HandleFunc("/games/{gameID}/hubs/{hubID}/messages", BroadcastMessage).Methods("POST")
+
+	authorized := v1.NewRoute().Subrouter()
+	authorized.Use(RequireAuth)
+	authorized.HandleFunc("/users/me", GetCurrentUser).Methods("GET")
+	authorized.HandleFunc("/games/{gameID}/states", ListSavedStates).Methods("GET")
+	authorized.HandleFunc("/games/{gameID}/states/{stateID}", GetSavedState).Methods("GET")
+	authorized.HandleFunc("/games/{gameID}/states/{stateID}", UpdateSavedState).Methods("PATCH")
+	authorized.HandleFunc("/games/{gameID}/states/{stateID}", DeleteSavedState).Methods("DELETE")
+	authorized.HandleFunc("/games/{gameID}/states/{stateID}/preview", GetSavedStatePreview).Methods("GET")
+	authorized.HandleFunc("/games/{gameID}/sessions", StartSession).Methods("POST")
+	authorized.HandleFunc("/games/{gameID}/sessions/{sessionID}", GetSession).Methods("GET")
+	authorized.HandleFunc("/games/{gameID}/sessions/{sessionID}/saves", SaveSession).Methods("POST")
+	authorized.HandleFunc("/games/{gameID}/sessions/{sessionID}/stats", GetSessionStats).Methods("GET")
+	authorized.HandleFunc("/games/{gameID}/sessions/{sessionID}/players", InvitePlayer).Methods("POST")
+}
+
+// CreateUser creates the user with its secret if it does not exist and issues a bearer token for it
+// Existing users are only issued tokens for the secret they were created with
+func CreateUser(w http.ResponseWriter, r *http.Request) {
+	user := userRequest{}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&user); err != nil || user.ID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "The request body must be a JSON object with an id and a secret.")
+		return
+	}
+	logger := annotateRequest(r, "user_id", user.ID)
+
+	if !allowRequest(w, r, LoginLimiter, user.ID) {
+		return
+	}
+
+	if len(user.Secret) < minSecretLength {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "The secret must be at least "+strconv.Itoa(minSecretLength)+" characters long.")
+		return
+	}
+
+	created, ok := checkCredential(user.ID, user.Secret)
+	if !ok {
+		logger.Warn("user credential rejected")
+		writeError(w, http.StatusUnauthorized, CodeInvalidCredentials, "The secret does not match the user's.")
+		return
+	}
+	status := http.StatusOK
+	if created {
+		logger.Info("user created")
+		status = http.StatusCreated
+	}
+
+	token, expires := IssueToken(user.ID)
+	writeJSON(w, status, Token{UserID: user.ID, Token: token, ExpiresAt: expires})
+}
+
+// GetCurrentUser returns the user the bearer token was issued to
+func GetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, User{ID: authenticatedUser(r)})
+}
+
+// StartSession starts a live game session for the caller, from a saved state if one is given
+func StartSession(w http.ResponseWriter, r *http.Request) {
+	gameID := mux.Vars(r)["gameID"]
+	userID := authenticatedUser(r)
+	if !gameExists(w, gameID) {
+		return
+	}
+
+	// The body is optional, an empty one starts a new game
+	request := sessionRequest{}
+	err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&request)
+	if err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "The request body must be a JSON object.")
+		return
+	}
+
+	var hub *Hub
+	var ok bool
+	if request.StateID == "" {
+		hub, ok = startSession(w, r, gameID, userID)
+	} else {
+		stateID := getValidStateID(w, r, request.StateID)
+		if stateID == -1 {
+			return
+		}
+		hub, ok = loadSession(w, r, gameID, userID, stateID)
+	}
+	if !ok {
+		return
+	}
+
+	session := newSession(hub)
+	w.Header().Set("Location", "/v1/games/"+gameID+"/sessions/"+session.ID)
+	writeJSON(w, http.StatusCreated, session)
+}
+
+// GetSession returns a live game session
+func GetSession(w http.ResponseWriter, r *http.Request) {
+	if hub, ok := liveSession(w, r); ok {
+		writeJSON(w, http.StatusOK, newSession(hub))
+	}
+}
+
+// SaveSession saves a live game session as a new state in the caller's list
+func SaveSession(w http.ResponseWriter, r *http.Request) {
+	hub, ok := liveSession(w, r)
+	if !ok {
+		return
+	}
+
+	gameID := hub.server.GetGameID()
+	if newState, ok := saveSession(w, r, gameID, authenticatedUser(r), hub.id); ok {
+		w.Header().Set("Location", "/v1/games/"+gameID+"/states/"+newState.ID)
+		writeJSON(w, http.StatusCreated, newState)
+	}
+}
+
+// GetSessionStats returns the latency statistics of each player in a live game session
+func GetSessionStats(w http.ResponseWriter, r *http.Request) {
+	if hub, ok := liveSession(w, r); ok {
+		writeJSON(w, http.StatusOK, hub.LatencyStats())
+	}
+}
+
+// PlaySession connects the caller to a live game session over a WebSocket
+func PlaySession(w http.ResponseWriter, r *http.Request) {
+	if hub, ok := liveSession(w, r); ok {
+		ServeWebSocket(authenticatedUser(r), hub.server.GetGameID(), hub.id, w, r)
+	}
+}
+
+// InvitePlayer allows another user to play in the caller's live game session
+func InvitePlayer(w http.ResponseWriter, r *http.Request) {
+	hub, ok := liveSession(w, r)
+	if !ok {
+		return
+	}
+	if hub.owner != authenticatedUser(r) {
+		writeError(w, http.StatusForbidden, CodeNotSessionPlayer, "Only the owner of the live game session can invite players.")
+		return
+	}
+
+	user := User{}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&user); err != nil || user.ID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "The request body must be a JSON object with an id.")
+		return
+	}
+	if !UserExists(user.ID) {
+		writeError(w, http.StatusNotFound, CodeUserNotFound, "User ID does not exist.")
+		return
+	}
+
+	hub.Invite(user.ID)
+	hub.logger.Info("player invited", "user_id", user.ID)
+	writeJSON(w, http.StatusOK, newSession(hub))
+}
+
+// liveSession returns the live game session in the request's path
+// Only its owner, its players and the users they invited can access it
+func liveSession(w http.ResponseWriter, r *http.Request) (*Hub, bool) {
+	params := mux.Vars(r)
+	gameID := params["gameID"]
+	if !gameExists(w, gameID) {
+		return nil, false
+	}
+
+	stateID := getValidStateID(w, r, params["sessionID"])
+	if stateID == -1 {
+		return nil, false
+	}
+	annotateRequest(r, "game_id", gameID, "state_id", stateID)
+
+	hub, ok := GetHub(gameID, stateID)
+	if !ok {
+		writeError(w, http.StatusNotFound, CodeNotLiveSession, "State ID is not a valid live game session.")
+		return nil, false
+	}
+	if !hub.IsMember(authenticatedUser(r)) {
+		writeError(w, http.StatusForbidden, CodeNotSessionPlayer, "The caller has not been invited to the live game session.")
+		return nil, false
+	}
+	return hub, true
+}
+
+// newSession returns the model of a live game session
+func newSession(hub *Hub) *Session {
+	return &Session{
+		ID:      strconv.Itoa(hub.id),
+		GameID:  hub.server.GetGameID(),
+		Owner:   hub.owner,
+		Players: hub.Players(),
+		Invited: hub.Invited(),
+	}
+}
@@ -0,0 +1,94 @@
+package platform
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/game-sharing/service/games/newgame"
+	"github.com/gorilla/mux"
+)
+
+// testAPI is the versioned API served against an in-memory Redis
+type testAPI struct {
+	t      *testing.T
+	server *httptest.Server
+}
+
+// newTestAPI serves the versioned API with the demo game and without rate limits
+// Live game sessions started by the test are stopped when it ends
+func newTestAPI(t *testing.T) *testAPI {
+	useTestDatabase(t)
+	Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	AppConfig.RateLimits = RateLimitConfig{}
+	HubCreationLimiter = NewRateLimiter(RateLimit{})
+	SaveLimiter = NewRateLimiter(RateLimit{})
+	LoginLimiter = NewRateLimiter(RateLimit{})
+
+	catalogueMux.Lock()
+	catalogue = map[GameID]Game{"0": DefaultGames[0]}
+	catalogueMux.Unlock()
+	GameServerMap = map[GameID]GameServer{"0": NewServerLogic("0", 0, &newgame.NewGameServer{})}
+	Users = make(map[UserID]bool)
+	credentials = make(map[UserID][sha256.Size]byte)
+	Hubs = make(map[HubKey]*Hub)
+	UserClients = make(map[UserID]*Client)
+
+	router := mux.NewRouter()
+	RegisterV1Routes(router)
+	router.HandleFunc("/games", GetGames).Methods("GET")
+	router.NotFoundHandler = http.HandlerFunc(NotFound)
+	router.MethodNotAllowedHandler = http.HandlerFunc(MethodNotAllowed)
+
+	api := &testAPI{t: t, server: httptest.NewServer(router)}
+	t.Cleanup(func() {
+		api.server.Close()
+		for _, hub := range LiveHubs() {
+			hub.Stop("The test is over.")
+		}
+	})
+	return api
+}
+
+// do sends a request with the bearer token, if any, returning the status and the decoded JSON body
+func (api *testAPI) do(method string, path string, token string, body string) (int, map[string]interface{}) {
+	api.t.Helper()
+	request, err := http.NewRequest(method, api.server.URL+path, strings.NewReader(body))
+	if err != nil {
+		api.t.Fatal(err)
+	}
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		api.t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	decoded := map[string]interface{}{}
+	json.NewDecoder(response.Body).Decode(&decoded)
+	return response.StatusCode, decoded
+}
+
+// login creates the user with a secret derived from its ID and returns a bearer token for it
+func (api *testAPI) login(userID UserID) string {
+	api.t.Helper()
+	status, body := api.do("POST", "/v1/users", "", `{"id":"`+userID+`","secret":"secret-`+userID+`"}`)
+	if status != http.StatusCreated && status != http.StatusOK {
+		api.t.Fatalf("logging in %s: got status %d, body %v", userID, status, body)
+	}
+	return body["token"].(string)
+}
+
+// errorCode returns the code of an error body, empty if the body is not an error
+func errorCode(body map[string]interface{}) string {
+	apiError, _ := body["error"].(map[string]interface{})
+	code, _ := apiError["code"].(string)
+	return code
+}
@@ -0,0 +1,366 @@
+package sdk
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Wire types of the binary codec, which follow the protocol buffers encoding
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// binaryCodec encodes models compactly as numbered fields, in the style of protocol buffers
+// Only fields tagged with a number, e.g. `binary:"1"`, are encoded. Fields can be added and
+// removed without breaking saved states as long as their numbers are never reused.
+// Zero values are not encoded, slices are repeated fields and maps are repeated key-value pairs.
+type binaryCodec struct{}
+
+// binaryField is a struct field encoded by the binary codec
+type binaryField struct {
+	index  int
+	number uint64
+}
+
+// binaryFieldCache holds the binary fields of each struct type
+var binaryFieldCache sync.Map
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (binaryCodec) Name() string {
+	return "binary"
+}
+
+func (binaryCodec) Marshal(model interface{}) ([]byte, error) {
+	value := reflect.ValueOf(model)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("binary codec: cannot encode %T, only structs", model)
+	}
+	return appendBinaryMessage(nil, value)
+}
+
+func (binaryCodec) Unmarshal(data []byte, model interface{}) error {
+	value := reflect.ValueOf(model)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("binary codec: cannot decode into %T, only pointers to structs", model)
+	}
+	return decodeBinaryMessage(data, value.Elem())
+}
+
+// binaryFields returns the numbered fields of a struct type
+func binaryFields(structType reflect.Type) ([]binaryField, error) {
+	if cached, ok := binaryFieldCache.Load(structType); ok {
+		return cached.([]binaryField), nil
+	}
+
+	fields := []binaryField{}
+	numbers := map[uint64]string{}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag, ok := field.Tag.Lookup("binary")
+		if !ok || tag == "-" {
+			continue
+		}
+		number, err := strconv.ParseUint(tag, 10, 32)
+		if err != nil || number == 0 || !field.IsExported() {
+			return nil, fmt.Errorf("binary codec: %s.%s must be exported with a positive field number", structType, field.Name)
+		}
+		if other, ok := numbers[number]; ok {
+			return nil, fmt.Errorf("binary codec: %s.%s reuses field number %d of %s", structType, field.Name, number, other)
+		}
+		numbers[number] = field.Name
+		fields = append(fields, binaryField{index: i, number: number})
+	}
+
+	binaryFieldCache.Store(structType, fields)
+	return fields, nil
+}
+
+// appendBinaryMessage appends the numbered fields of a struct that are not zero
+func appendBinaryMessage(buffer []byte, value reflect.Value) ([]byte, error) {
+	fields, err := binaryFields(value.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range fields {
+		fieldValue := value.Field(field.index)
+		switch {
+		case fieldValue.IsZero():
+			continue
+		case fieldValue.Kind() == reflect.Slice && fieldValue.Type().Elem().Kind() != reflect.Uint8:
+			for i := 0; i < fieldValue.Len(); i++ {
+				if buffer, err = appendBinaryValue(buffer, field.number, fieldValue.Index(i)); err != nil {
+					return nil, err
+				}
+			}
+		case fieldValue.Kind() == reflect.Map:
+			iterator := fieldValue.MapRange()
+			for iterator.Next() {
+				entry, err := appendBinaryValue(nil, 1, iterator.Key())
+				if err == nil {
+					entry, err = appendBinaryValue(entry, 2, iterator.Value())
+				}
+				if err != nil {
+					return nil, err
+				}
+				buffer = appendBinaryBytes(buffer, field.number, entry)
+			}
+		default:
+			if buffer, err = appendBinaryValue(buffer, field.number, fieldValue); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return buffer, nil
+}
+
+// appendBinaryValue appends a single value as the numbered field
+func appendBinaryValue(buffer []byte, number uint64, value reflect.Value) ([]byte, error) {
+	if value.Type() == timeType {
+		encoded, err := value.Interface().(time.Time).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		return appendBinaryBytes(buffer, number, encoded), nil
+	}
+
+	switch value.Kind() {
+	case reflect.Bool:
+		var flag uint64
+		if value.Bool() {
+			flag = 1
+		}
+		return appendBinaryVarint(buffer, number, flag), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// Zigzag encoding keeps small negative numbers short
+		signed := value.Int()
+		return appendBinaryVarint(buffer, number, uint64(signed<<1)^uint64(signed>>63)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return appendBinaryVarint(buffer, number, value.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		buffer = binary.AppendUvarint(buffer, number<<3|wireFixed64)
+		return binary.LittleEndian.AppendUint64(buffer, math.Float64bits(value.Float())), nil
+	case reflect.String:
+		return appendBinaryBytes(buffer, number, []byte(value.String())), nil
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return appendBinaryBytes(buffer, number, value.Bytes()), nil
+		}
+	case reflect.Struct:
+		message, err := appendBinaryMessage(nil, value)
+		if err != nil {
+			return nil, err
+		}
+		return appendBinaryBytes(buffer, number, message), nil
+	case reflect.Ptr:
+		if value.IsNil() {
+			value = reflect.New(value.Type().Elem())
+		}
+		return appendBinaryValue(buffer, number, value.Elem())
+	}
+	return nil, fmt.Errorf("binary codec: cannot encode field %d of type %s", number, value.Type())
+}
+
+func appendBinaryVarint(buffer []byte, number uint64, value uint64) []byte {
+	buffer = binary.AppendUvarint(buffer, number<<3|wireVarint)
+	return binary.AppendUvarint(buffer, value)
+}
+
+func appendBinaryBytes(buffer []byte, number uint64, value []byte) []byte {
+	buffer = binary.AppendUvarint(buffer, number<<3|wireBytes)
+	buffer = binary.AppendUvarint(buffer, uint64(len(value)))
+	return append(buffer, value...)
+}
+
+// binaryWireValue is a field read from an encoded message
+type binaryWireValue struct {
+	wireType int
+	number   uint64
+	bits     uint64
+	bytes    []byte
+}
+
+// errBinaryTruncated is returned when an encoded message ends in the middle of a field
+var errBinaryTruncated = errors.New("binary codec: message is truncated")
+
+// readBinaryField reads the field at the start of data, returning the rest of data
+func readBinaryField(data []byte) (binaryWireValue, []byte, error) {
+	key, n := binary.Uvarint(data)
+	if n <= 0 {
+		return binaryWireValue{}, nil, errBinaryTruncated
+	}
+	data = data[n:]
+	field := binaryWireValue{wireType: int(key & 7), number: key >> 3}
+
+	switch field.wireType {
+	case wireVarint:
+		field.bits, n = binary.Uvarint(data)
+		if n <= 0 {
+			return field, nil, errBinaryTruncated
+		}
+		return field, data[n:], nil
+	case wireFixed64:
+		if len(data) < 8 {
+			return field, nil, errBinaryTruncated
+		}
+		field.bits = binary.LittleEndian.Uint64(data)
+		return field, data[8:], nil
+	case wireBytes:
+		length, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < length {
+			return field, nil, errBinaryTruncated
+		}
+		field.bytes = data[n : n+int(length)]
+		return field, data[n+int(length):], nil
+	default:
+		return field, nil, fmt.Errorf("binary codec: unknown wire type %d of field %d", field.wireType, field.number)
+	}
+}
+
+// decodeBinaryMessage sets the fields of a struct from an encoded message, skipping unknown fields
+func decodeBinaryMessage(data []byte, value reflect.Value) error {
+	fields, err := binaryFields(value.Type())
+	if err != nil {
+		return err
+	}
+	indexes := make(map[uint64]int, len(fields))
+	for _, field := range fields {
+		indexes[field.number] = field.index
+	}
+
+	for len(data) > 0 {
+		var field binaryWireValue
+		if field, data, err = readBinaryField(data); err != nil {
+			return err
+		}
+		index, ok := indexes[field.number]
+		if !ok {
+			continue
+		}
+
+		fieldValue := value.Field(index)
+		switch {
+		case fieldValue.Kind() == reflect.Slice && fieldValue.Type().Elem().Kind() != reflect.Uint8:
+			element := reflect.New(fieldValue.Type().Elem()).Elem()
+			if err := decodeBinaryValue(field, element); err != nil {
+				return err
+			}
+			fieldValue.Set(reflect.Append(fieldValue, element))
+		case fieldValue.Kind() == reflect.Map:
+			if err := decodeBinaryMapEntry(field, fieldValue); err != nil {
+				return err
+			}
+		default:
+			if err := decodeBinaryValue(field, fieldValue); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decodeBinaryMapEntry adds a key-value pair to a map
+func decodeBinaryMapEntry(field binaryWireValue, mapValue reflect.Value) error {
+	if field.wireType != wireBytes {
+		return fmt.Errorf("binary codec: field %d has wire type %d, expected a map entry", field.number, field.wireType)
+	}
+	if mapValue.IsNil() {
+		mapValue.Set(reflect.MakeMap(mapValue.Type()))
+	}
+
+	key := reflect.New(mapValue.Type().Key()).Elem()
+	element := reflect.New(mapValue.Type().Elem()).Elem()
+	data := field.bytes
+	for len(data) > 0 {
+		var entryField binaryWireValue
+		var err error
+		if entryField, data, err = readBinaryField(data); err != nil {
+			return err
+		}
+		switch entryField.number {
+		case 1:
+			err = decodeBinaryValue(entryField, key)
+		case 2:
+			err = decodeBinaryValue(entryField, element)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	mapValue.SetMapIndex(key, element)
+	return nil
+}
+
+// decodeBinaryValue sets a single value from a field
+func decodeBinaryValue(field binaryWireValue, value reflect.Value) error {
+	expected := wireBytes
+	switch value.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		expected = wireVarint
+	case reflect.Float32, reflect.Float64:
+		expected = wireFixed64
+	case reflect.Ptr:
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		return decodeBinaryValue(field, value.Elem())
+	}
+	if value.Type() == timeType {
+		expected = wireBytes
+	}
+	if field.wireType != expected {
+		return fmt.Errorf("binary codec: field %d has wire type %d, expected %d for %s", field.number, field.wireType, expected, value.Type())
+	}
+
+	if value.Type() == timeType {
+		var decoded time.Time
+		if err := decoded.UnmarshalBinary(field.bytes); err != nil {
+			return fmt.Errorf("binary codec: field %d: %w", field.number, err)
+		}
+		value.Set(reflect.ValueOf(decoded))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Bool:
+		value.SetBool(field.bits != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		signed := int64(field.bits>>1) ^ -int64(field.bits&1)
+		if value.OverflowInt(signed) {
+			return fmt.Errorf("binary codec: field %d overflows %s", field.number, value.Type())
+		}
+		value.SetInt(signed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value.OverflowUint(field.bits) {
+			return fmt.Errorf("binary codec: field %d overflows %s", field.number, value.Type())
+		}
+		value.SetUint(field.bits)
+	case reflect.Float32, reflect.Float64:
+		value.SetFloat(math.Float64frombits(field.bits))
+	case reflect.String:
+		value.SetString(string(field.bytes))
+	case reflect.Slice:
+		if value.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("binary codec: cannot decode field %d into %s", field.number, value.Type())
+		}
+		value.SetBytes(append([]byte{}, field.bytes...))
+	case reflect.Struct:
+		return decodeBinaryMessage(field.bytes, value)
+	default:
+		return fmt.Errorf("binary codec: cannot decode field %d into %s", field.number, value.Type())
+	}
+	return nil
+}
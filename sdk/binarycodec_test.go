@@ -0,0 +1,218 @@
+package sdk
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type binaryPoint struct {
+	X int32 `binary:"1"`
+	Y int32 `binary:"2"`
+}
+
+type binaryKinds struct {
+	Bool    bool                `binary:"1"`
+	Int     int                 `binary:"2"`
+	Int8    int8                `binary:"3"`
+	Int16   int16               `binary:"4"`
+	Int32   int32               `binary:"5"`
+	Int64   int64               `binary:"6"`
+	Uint    uint                `binary:"7"`
+	Uint8   uint8               `binary:"8"`
+	Uint16  uint16              `binary:"9"`
+	Uint32  uint32              `binary:"10"`
+	Uint64  uint64              `binary:"11"`
+	Float32 float32             `binary:"12"`
+	Float64 float64             `binary:"13"`
+	String  string              `binary:"14"`
+	Bytes   []byte              `binary:"15"`
+	Time    time.Time           `binary:"16"`
+	Point   binaryPoint         `binary:"17"`
+	Pointer *binaryPoint        `binary:"18"`
+	Number  *int                `binary:"19"`
+	Strings []string            `binary:"20"`
+	Points  []binaryPoint       `binary:"21"`
+	Scores  map[string]int      `binary:"22"`
+	Named   map[int]binaryPoint `binary:"23"`
+	Ignored string
+	Skipped string `binary:"-"`
+}
+
+// binaryRoundTrip encodes the model and decodes it into a new value of the same type
+func binaryRoundTrip(t *testing.T, model interface{}) interface{} {
+	t.Helper()
+	data, err := binaryCodec{}.Marshal(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := reflect.New(reflect.TypeOf(model).Elem())
+	if err := (binaryCodec{}).Unmarshal(data, decoded.Interface()); err != nil {
+		t.Fatal(err)
+	}
+	return decoded.Interface()
+}
+
+func TestBinaryCodecRoundTrip(t *testing.T) {
+	number := -7
+	model := &binaryKinds{
+		Bool:    true,
+		Int:     -1234567,
+		Int8:    math.MinInt8,
+		Int16:   math.MaxInt16,
+		Int32:   -1,
+		Int64:   math.MinInt64,
+		Uint:    1234567,
+		Uint8:   math.MaxUint8,
+		Uint16:  math.MaxUint16,
+		Uint32:  math.MaxUint32,
+		Uint64:  math.MaxUint64,
+		Float32: 1.5,
+		Float64: -math.Pi,
+		String:  "héllo",
+		Bytes:   []byte{0, 1, 2, 255},
+		Time:    time.Date(2026, 1, 2, 15, 4, 5, 6, time.UTC),
+		Point:   binaryPoint{X: 3, Y: -4},
+		Pointer: &binaryPoint{X: 5},
+		Number:  &number,
+		Strings: []string{"a", "", "c"},
+		Points:  []binaryPoint{{X: 1}, {}, {Y: 2}},
+		Scores:  map[string]int{"alice": 3, "bob": 0, "": -1},
+		Named:   map[int]binaryPoint{0: {X: 1}, -2: {}},
+	}
+
+	decoded := binaryRoundTrip(t, model)
+	if !reflect.DeepEqual(decoded, model) {
+		t.Fatalf("got %+v, want %+v", decoded, model)
+	}
+}
+
+func TestBinaryCodecZeroValues(t *testing.T) {
+	data, err := binaryCodec{}.Marshal(&binaryKinds{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 0 {
+		t.Errorf("zero values encoded as %d bytes, want none", len(data))
+	}
+	if decoded := binaryRoundTrip(t, &binaryKinds{}); !reflect.DeepEqual(decoded, &binaryKinds{}) {
+		t.Errorf("got %+v, want zero values", decoded)
+	}
+
+	// Pointers to zero values are kept, unlike nil pointers
+	zero := 0
+	model := &binaryKinds{Pointer: &binaryPoint{}, Number: &zero}
+	if decoded := binaryRoundTrip(t, model); !reflect.DeepEqual(decoded, model) {
+		t.Errorf("got %+v, want %+v", decoded, model)
+	}
+}
+
+func TestBinaryCodecUntaggedFields(t *testing.T) {
+	decoded := binaryRoundTrip(t, &binaryKinds{Ignored: "a", Skipped: "b"}).(*binaryKinds)
+	if decoded.Ignored != "" || decoded.Skipped != "" {
+		t.Errorf("untagged fields were encoded: %+v", decoded)
+	}
+}
+
+type binaryStateV1 struct {
+	Name string `binary:"1"`
+}
+
+type binaryStateV2 struct {
+	Name   string          `binary:"1"`
+	Score  int             `binary:"2"`
+	Ratio  float64         `binary:"3"`
+	Points []binaryPoint   `binary:"4"`
+	Tags   map[string]bool `binary:"5"`
+}
+
+func TestBinaryCodecUnknownFields(t *testing.T) {
+	newer := &binaryStateV2{Name: "alice", Score: -3, Ratio: 0.5, Points: []binaryPoint{{X: 1}}, Tags: map[string]bool{"a": true}}
+	data, err := binaryCodec{}.Marshal(newer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fields removed since the state was saved are skipped
+	older := &binaryStateV1{}
+	if err := (binaryCodec{}).Unmarshal(data, older); err != nil {
+		t.Fatal(err)
+	}
+	if older.Name != "alice" {
+		t.Errorf("got name %q, want %q", older.Name, "alice")
+	}
+
+	// Fields added since the state was saved are left as zero values
+	data, err = binaryCodec{}.Marshal(&binaryStateV1{Name: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	upgraded := &binaryStateV2{}
+	if err := (binaryCodec{}).Unmarshal(data, upgraded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(upgraded, &binaryStateV2{Name: "bob"}) {
+		t.Errorf("got %+v, want only the name", upgraded)
+	}
+}
+
+func TestBinaryCodecInvalidStructs(t *testing.T) {
+	type reusedNumber struct {
+		A int `binary:"1"`
+		B int `binary:"1"`
+	}
+	type zeroNumber struct {
+		A int `binary:"0"`
+	}
+	type unexported struct {
+		a int `binary:"1"`
+	}
+	for _, model := range []interface{}{&reusedNumber{A: 1}, &zeroNumber{A: 1}, &unexported{a: 1}} {
+		if _, err := (binaryCodec{}).Marshal(model); err == nil {
+			t.Errorf("encoding %T succeeded, want an error", model)
+		}
+	}
+	if _, err := (binaryCodec{}).Marshal(42); err == nil {
+		t.Error("encoding an int succeeded, want an error")
+	}
+	if err := (binaryCodec{}).Unmarshal(nil, binaryStateV1{}); err == nil {
+		t.Error("decoding into a struct value succeeded, want an error")
+	}
+}
+
+func TestBinaryCodecMalformedInput(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated key", []byte{0x80}},
+		{"truncated varint", []byte{1<<3 | wireVarint, 0x80}},
+		{"truncated fixed64", []byte{3<<3 | wireFixed64, 0, 0, 0}},
+		{"truncated length", []byte{1<<3 | wireBytes, 0x80}},
+		{"length past the end", []byte{1<<3 | wireBytes, 5, 'a', 'b'}},
+		{"oversized length", []byte{1<<3 | wireBytes, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+		{"overflowing key", []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+		{"unknown wire type", []byte{1<<3 | 5, 0, 0, 0, 0}},
+		{"wrong wire type", []byte{1<<3 | wireVarint, 1}},
+		{"truncated nested message", []byte{4<<3 | wireBytes, 2, 1<<3 | wireVarint, 0x80}},
+		{"truncated map entry", []byte{5<<3 | wireBytes, 2, 1<<3 | wireBytes, 9}},
+		{"overflowing int32", []byte{4<<3 | wireBytes, 6, 1<<3 | wireVarint, 0xff, 0xff, 0xff, 0xff, 0x7f}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := (binaryCodec{}).Unmarshal(test.data, &binaryStateV2{}); err == nil {
+				t.Error("decoding succeeded, want an error")
+			}
+		})
+	}
+
+	// Every prefix of a valid message decodes or fails without panicking
+	data, err := binaryCodec{}.Marshal(&binaryKinds{String: "abc", Float64: 1, Points: []binaryPoint{{X: 1}}, Scores: map[string]int{"a": 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range data {
+		(binaryCodec{}).Unmarshal(data[:i], &binaryKinds{})
+	}
+}
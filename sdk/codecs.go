@@ -0,0 +1,69 @@
+package sdk
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// StateCodec encodes and decodes the model a game saves its state as
+type StateCodec interface {
+	// Name identifies the codec in the header of saved states, so it must not change once states are saved with it
+	Name() string
+	Marshal(model interface{}) ([]byte, error)
+	Unmarshal(data []byte, model interface{}) error
+}
+
+// The codecs games can save their states with
+var (
+	JSONCodec   StateCodec = jsonCodec{}
+	GobCodec    StateCodec = gobCodec{}
+	BinaryCodec StateCodec = binaryCodec{}
+)
+
+// stateCodecs finds the codec named in the header of a saved state
+var stateCodecs = map[string]StateCodec{
+	JSONCodec.Name():   JSONCodec,
+	GobCodec.Name():    GobCodec,
+	BinaryCodec.Name(): BinaryCodec,
+}
+
+// LookupStateCodec returns the codec with the name
+func LookupStateCodec(name string) (StateCodec, bool) {
+	codec, ok := stateCodecs[name]
+	return codec, ok
+}
+
+// jsonCodec encodes models as JSON, which is easy to inspect in the database
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+func (jsonCodec) Marshal(model interface{}) ([]byte, error) {
+	return json.Marshal(model)
+}
+
+func (jsonCodec) Unmarshal(data []byte, model interface{}) error {
+	return json.Unmarshal(data, model)
+}
+
+// gobCodec encodes models with encoding/gob, which needs no field tags
+type gobCodec struct{}
+
+func (gobCodec) Name() string {
+	return "gob"
+}
+
+func (gobCodec) Marshal(model interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(model); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, model interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(model)
+}
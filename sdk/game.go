@@ -0,0 +1,55 @@
+// Package sdk is the contract between games and the Game Sharing Service platform
+// Games import only this package, so they can live in their own repositories
+package sdk
+
+import "image"
+
+// DisplayData is what the players' screen displays
+type DisplayData = []byte
+
+// InputData is the format of the players' controls
+type InputData = []byte
+
+// Game is implemented by every game, the platform handles sessions, players and saving around it
+type Game interface {
+	// NewState returns the state of a new game
+	NewState() GameState
+
+	// Run the game logic given certain inputs
+	// The GameState will be updated along with new DisplayData
+	ProcessState(GameState, InputData)
+
+	// The codec the game's states are saved with, which must not change once states are saved
+	StateCodec() StateCodec
+}
+
+// GameState is a game's own state, the platform keeps the session's metadata around it
+type GameState interface {
+	GetDisplayData() DisplayData
+
+	// Encode and decode the state with the codec
+	MarshalState(StateCodec) ([]byte, error)
+	UnmarshalState(StateCodec, []byte) error
+}
+
+// PreviewRenderer is implemented by games that can draw their display data
+// Saved states of games with a renderer get a PNG preview of the moment they capture
+type PreviewRenderer interface {
+	RenderPreview(DisplayData) (image.Image, error)
+}
+
+// Versioned is implemented by games that report their release
+// The version is recorded with each state the game saves, so it must be set by the game rather than an operator
+type Versioned interface {
+	GameVersion() string
+}
+
+// StateMigration upgrades a game's encoded state from one schema version to the next
+// The state is encoded with the codec it was saved with, and must be returned encoded with the same codec
+type StateMigration func(codec StateCodec, state []byte) ([]byte, error)
+
+// StateMigrator is implemented by games whose state format has changed since states were first saved
+// The first migration upgrades schema version 1, so a game with n migrations saves version n+1
+type StateMigrator interface {
+	StateMigrations() []StateMigration
+}
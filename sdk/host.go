@@ -0,0 +1,197 @@
+package sdk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+)
+
+// The game host protocol runs a game as a separate process from the platform. The platform calls the
+// methods of the Game service with JSON-RPC 1.0, over the process's stdin and stdout, or over a Unix
+// socket when it sets SocketEnv. States are passed encoded with the game's codec, so the process keeps
+// no state between calls and can be restarted at any time.
+//
+//	Game.Describe(struct{})           HostDescription
+//	Game.NewState(struct{})           HostState
+//	Game.LoadState([]byte)            HostState
+//	Game.ProcessState(ProcessRequest) HostState
+//	Game.MigrateState(MigrateRequest) []byte
+//	Game.RenderPreview(DisplayData)   []byte, a PNG
+const ProtocolVersion = 1
+
+// SocketEnv names the environment variable holding the Unix socket a game process connects to
+const SocketEnv = "GAME_SHARING_SOCKET"
+
+// ErrNoPreview is returned by RenderPreview calls to games that do not implement PreviewRenderer
+var ErrNoPreview = errors.New("game does not render previews")
+
+// HostDescription tells the platform how to run a game process
+type HostDescription struct {
+	ProtocolVersion int    `json:"protocolVersion"`
+	Codec           string `json:"codec"`
+	Migrations      int    `json:"migrations"`
+	Previews        bool   `json:"previews"`
+	Version         string `json:"version,omitempty"`
+}
+
+// HostState is a game's state encoded with its codec, along with what it displays
+type HostState struct {
+	State   []byte      `json:"state"`
+	Display DisplayData `json:"display"`
+}
+
+// ProcessRequest asks a game process to run one tick of a state
+// An empty state runs the tick on a new state
+type ProcessRequest struct {
+	State []byte    `json:"state"`
+	Input InputData `json:"input"`
+}
+
+// MigrateRequest asks a game process to upgrade a state from a schema version to the next
+type MigrateRequest struct {
+	Version int    `json:"version"`
+	State   []byte `json:"state"`
+}
+
+// Serve runs a game as a separate process, answering the platform's calls until it disconnects
+// Anything the game writes to stdout is sent to stderr instead, which the platform logs
+func Serve(game Game) error {
+	var conn io.ReadWriteCloser = stdio{Reader: os.Stdin, Writer: os.Stdout}
+	os.Stdout = os.Stderr
+
+	if socket := os.Getenv(SocketEnv); socket != "" {
+		unixConn, err := net.Dial("unix", socket)
+		if err != nil {
+			return err
+		}
+		conn = unixConn
+	}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("Game", &gameHost{game: game}); err != nil {
+		return err
+	}
+	server.ServeCodec(jsonrpc.NewServerCodec(conn))
+	return nil
+}
+
+// stdio joins stdin and stdout into a connection
+type stdio struct {
+	io.Reader
+	io.Writer
+}
+
+func (stdio) Close() error {
+	return nil
+}
+
+// gameHost answers the platform's calls with a game
+// A panicking game fails the call rather than the process
+type gameHost struct {
+	game Game
+}
+
+func (host *gameHost) Describe(_ struct{}, reply *HostDescription) error {
+	reply.ProtocolVersion = ProtocolVersion
+	reply.Codec = host.game.StateCodec().Name()
+	if migrator, ok := host.game.(StateMigrator); ok {
+		reply.Migrations = len(migrator.StateMigrations())
+	}
+	_, reply.Previews = host.game.(PreviewRenderer)
+	if versioned, ok := host.game.(Versioned); ok {
+		reply.Version = versioned.GameVersion()
+	}
+	return nil
+}
+
+func (host *gameHost) NewState(_ struct{}, reply *HostState) (err error) {
+	defer recoverCall(&err)
+	return host.reply(host.game.NewState(), reply)
+}
+
+func (host *gameHost) LoadState(state []byte, reply *HostState) (err error) {
+	defer recoverCall(&err)
+	loaded, err := host.decode(state)
+	if err != nil {
+		return err
+	}
+	return host.reply(loaded, reply)
+}
+
+func (host *gameHost) ProcessState(request ProcessRequest, reply *HostState) (err error) {
+	defer recoverCall(&err)
+	state, err := host.decode(request.State)
+	if err != nil {
+		return err
+	}
+	host.game.ProcessState(state, request.Input)
+	return host.reply(state, reply)
+}
+
+func (host *gameHost) MigrateState(request MigrateRequest, reply *[]byte) (err error) {
+	defer recoverCall(&err)
+	migrator, ok := host.game.(StateMigrator)
+	if !ok {
+		return fmt.Errorf("no migration from schema version %d", request.Version)
+	}
+	migrations := migrator.StateMigrations()
+	if request.Version < 1 || request.Version > len(migrations) {
+		return fmt.Errorf("no migration from schema version %d", request.Version)
+	}
+	*reply, err = migrations[request.Version-1](host.game.StateCodec(), request.State)
+	return err
+}
+
+func (host *gameHost) RenderPreview(display DisplayData, reply *[]byte) (err error) {
+	defer recoverCall(&err)
+	renderer, ok := host.game.(PreviewRenderer)
+	if !ok {
+		return ErrNoPreview
+	}
+	img, err := renderer.RenderPreview(display)
+	if err != nil {
+		return err
+	}
+	encoded := &bytes.Buffer{}
+	if err := png.Encode(encoded, img); err != nil {
+		return err
+	}
+	*reply = encoded.Bytes()
+	return nil
+}
+
+// decode returns the state encoded with the game's codec, or a new state if it is empty
+func (host *gameHost) decode(data []byte) (GameState, error) {
+	state := host.game.NewState()
+	if len(data) == 0 {
+		return state, nil
+	}
+	if err := state.UnmarshalState(host.game.StateCodec(), data); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// reply encodes the state into the reply with the game's codec
+func (host *gameHost) reply(state GameState, reply *HostState) error {
+	encoded, err := state.MarshalState(host.game.StateCodec())
+	if err != nil {
+		return err
+	}
+	reply.State = encoded
+	reply.Display = state.GetDisplayData()
+	return nil
+}
+
+// recoverCall turns a panic in the game into the error of the call
+func recoverCall(err *error) {
+	if recovered := recover(); recovered != nil {
+		*err = fmt.Errorf("game panicked: %v", recovered)
+	}
+}
@@ -1,163 +0,0 @@
-package main
-
-import (
-	"log"
-	"net/http"
-	"time"
-
-	"github.com/gorilla/websocket"
-)
-
-const (
-	// Time allowed to write a message to the peer.
-	writeWait = 10 * time.Second
-
-	// Time allowed to read the next pong message from the peer.
-	pongWait = 60 * time.Second
-
-	// Send pings to peer with this period. Must be less than pongWait.
-	pingPeriod = (pongWait * 9) / 10
-
-	// Maximum message size allowed from peer.
-	maxMessageSize = 16
-)
-
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true // TODO: Replace with actual origin check!!!
-	},
-}
-
-// Client is a middleman between the websocket connection and the hub
-type Client struct {
-	// Represents the game that is currently active
-	hub *Hub
-
-	// The websocket connection.
-	conn *websocket.Conn
-
-	// Buffered channel of outbound messages.
-	send chan DisplayData
-}
-
-// DisplayData is what the players' screen displays
-type DisplayData = []byte
-
-// InputData is the format of the players' controls
-type InputData = []byte
-
-// readPump pumps messages from the websocket connection to the hub.
-//
-// The application runs readPump in a per-connection goroutine. The application
-// ensures that there is at most one reader on a connection by executing all
-// reads from this goroutine.
-func (c *Client) readPump() {
-	// Makes sure to close and unregister the client
-	defer func() {
-		c.hub.unregister <- c
-		c.conn.Close()
-	}()
-
-	// Sets connection contraints
-	c.conn.SetReadLimit(maxMessageSize)
-	c.conn.SetReadDeadline(time.Now().Add(pongWait))
-	c.conn.SetPongHandler(func(string) error { c.conn.SetReadDeadline(time.Now().Add(pongWait)); return nil })
-
-	// Continuously reads messages until an error occurs
-	for {
-		_, message, err := c.conn.ReadMessage()
-
-		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("error: %v", err)
-			}
-			break
-		}
-
-		// switch statement for game input (g), pause (p), unpause (u), save (s), saveas (a)
-		c.hub.broadcast <- message
-	}
-}
-
-// writePump pumps messages from the hub to the websocket connection.
-//
-// A goroutine running writePump is started for each connection. The
-// application ensures that there is at most one writer to a connection by
-// executing all writes from this goroutine.
-func (c *Client) writePump() {
-	// Limit the ping period
-	ticker := time.NewTicker(pingPeriod)
-
-	// Ensure connection is closed and ticker is stopped
-	defer func() {
-		ticker.Stop()
-		c.conn.Close()
-	}()
-
-	// Continuously writes messages
-	for {
-		select {
-		case message, ok := <-c.send:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if !ok {
-				// The hub closed the channel.
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
-			}
-
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
-			}
-			w.Write(message)
-
-			// Add queued outputs to the current websocket message
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				w.Write(<-c.send)
-			}
-
-			if err := w.Close(); err != nil {
-				return
-			}
-		case <-ticker.C:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
-				return
-			}
-		}
-
-	}
-}
-
-// ServeWebSocket handles websocket requests from the peer.
-func ServeWebSocket(userID UserID, stateID StateID, w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
-
-	if err != nil {
-		log.Println(err)
-		return
-	}
-
-	// Unregister the user from existing hubs
-	client, existingUser := UserClients[userID]
-
-	if existingUser {
-		client.hub.unregister <- client
-	} else {
-		// Create a new client
-		hub := Hubs[stateID]
-		client = &Client{hub: hub, conn: conn, send: make(chan DisplayData, 256)}
-	}
-
-	// Register client to the correct hub
-	UserClients[userID] = client
-	client.hub.register <- client
-
-	// Allow collection of memory referenced by the caller by doing all work in
-	// new goroutines.
-	go client.writePump()
-	go client.readPump()
-}